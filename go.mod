@@ -40,11 +40,13 @@ require (
 )
 
 require (
+	github.com/chai2010/webp v1.1.1
 	github.com/gin-gonic/gin v1.10.1
+	github.com/go-fonts/liberation v0.1.1
 	github.com/google/uuid v1.6.0
 	github.com/redis/go-redis/v9 v9.12.1
 	github.com/streadway/amqp v1.1.0
 	github.com/supabase-community/storage-go v0.7.0
 	go.uber.org/zap v1.27.0
-	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8
+	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
 )
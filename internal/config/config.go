@@ -43,6 +43,8 @@ type StorageConfig struct {
 	MaxFileSize   int64
 	AllowedTypes  []string
 	UploadPath    string
+	WatermarkDir  string
+	FontDir       string
 	CacheDuration time.Duration
 }
 
@@ -72,8 +74,10 @@ func Load() (*Config, error) {
 		},
 		Storage: StorageConfig{
 			MaxFileSize:   getEnvAsInt64("MAX_FILE_SIZE", 10*1024*1024), // 10MB
-			AllowedTypes:  []string{"image/jpeg", "image/png", "image/webp"},
+			AllowedTypes:  []string{"image/jpeg", "image/png", "image/webp", "image/gif", "image/bmp", "image/tiff"},
 			UploadPath:    getEnv("UPLOAD_PATH", "./uploads"),
+			WatermarkDir:  getEnv("WATERMARK_DIR", "./watermarks"),
+			FontDir:       getEnv("FONT_DIR", "./fonts"),
 			CacheDuration: getDuration("CACHE_DURATION", 24*time.Hour),
 		},
 	}
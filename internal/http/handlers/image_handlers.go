@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/phambaophuc/image-resize/internal/config"
 	"github.com/phambaophuc/image-resize/internal/models"
 	"github.com/phambaophuc/image-resize/internal/services"
@@ -16,6 +18,9 @@ const (
 	maxCacheAge    = 3600
 	imageParamKey  = "image"
 	imagesParamKey = "images"
+
+	composeFilename = "collage"
+	spriteFilename  = "sprite"
 )
 
 type ImageHandler struct {
@@ -88,6 +93,11 @@ func (h *ImageHandler) BatchResize(c *gin.Context) {
 		return
 	}
 
+	if err := h.validateBatchResizeRequest(req); err != nil {
+		h.respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	openedFiles, err := h.openFiles(files)
 	if err != nil {
 		h.respondError(c, http.StatusInternalServerError, "Failed to open files: "+err.Error())
@@ -96,7 +106,7 @@ func (h *ImageHandler) BatchResize(c *gin.Context) {
 	defer h.closeFiles(openedFiles)
 
 	images := h.processor.BatchResize(openedFiles, req)
-	response := h.buildBatchResponse(c.Request.Context(), images, files, req.Resize.Format)
+	response := h.buildBatchResponse(c.Request.Context(), images, files)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -104,6 +114,121 @@ func (h *ImageHandler) BatchResize(c *gin.Context) {
 	})
 }
 
+func (h *ImageHandler) ComposeImages(c *gin.Context) {
+	files, err := h.parseMultipartFiles(c)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := h.parseComposeParams(c, len(files))
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	openedFiles, err := h.openFiles(files)
+	if err != nil {
+		h.respondError(c, http.StatusInternalServerError, "Failed to open files: "+err.Error())
+		return
+	}
+	defer h.closeFiles(openedFiles)
+
+	buffer, format, composed, err := h.processor.ComposeGrid(openedFiles, h.config.Storage.MaxFileSize, req)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, fmt.Sprintf("Failed to compose images: %v", err))
+		return
+	}
+
+	imageURL := h.uploadBuffer(c.Request.Context(), buffer, composeFilename+"."+format, format)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: models.ProcessedImage{
+			ID:          uuid.New().String(),
+			URL:         imageURL,
+			FileSize:    int64(buffer.Len()),
+			ProcessedAt: time.Now(),
+			Size: models.ResizeSize{
+				Width:   composed.Bounds().Dx(),
+				Height:  composed.Bounds().Dy(),
+				Quality: req.Quality,
+				Format:  format,
+			},
+		},
+	})
+}
+
+func (h *ImageHandler) SpriteSheet(c *gin.Context) {
+	files, err := h.parseMultipartFiles(c)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := h.parseSpriteParams(c, len(files))
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	openedFiles, err := h.openFiles(files)
+	if err != nil {
+		h.respondError(c, http.StatusInternalServerError, "Failed to open files: "+err.Error())
+		return
+	}
+	defer h.closeFiles(openedFiles)
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Filename
+	}
+
+	sheet, err := h.processor.BuildSpriteSheet(openedFiles, names, h.config.Storage.MaxFileSize, req)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, fmt.Sprintf("Failed to build sprite sheet: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    h.buildSpriteResponse(c.Request.Context(), sheet, req),
+	})
+}
+
+func (h *ImageHandler) ExtractPalette(c *gin.Context) {
+	file, _, err := h.getUploadedFile(c, imageParamKey)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, "No image file provided")
+		return
+	}
+	defer file.Close()
+
+	size := services.DefaultPaletteSize
+	if value := c.PostForm("colors"); value != "" {
+		size, err = h.parsePositiveInt(value, "colors")
+		if err != nil {
+			h.respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if size > services.MaxPaletteSize {
+			h.respondError(c, http.StatusBadRequest, fmt.Sprintf("colors must be at most %d", services.MaxPaletteSize))
+			return
+		}
+	}
+
+	palette, err := h.processor.ExtractPalette(file, h.config.Storage.MaxFileSize, size)
+	if err != nil {
+		h.respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid image: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    palette,
+	})
+}
+
 // HealthCheck
 func (h *ImageHandler) HealthCheck(c *gin.Context) {
 	storageStatus := h.storage.HealthCheck(c.Request.Context())
@@ -8,6 +8,7 @@ import (
 	"image"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -16,33 +17,145 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/phambaophuc/image-resize/internal/models"
+	"github.com/phambaophuc/image-resize/internal/services"
 	"go.uber.org/zap"
 )
 
 // === REQUEST PARSING ===
 
 func (h *ImageHandler) parseResizeParams(c *gin.Context) (*models.AdvancedProcessingRequest, error) {
-	width, err := h.parsePositiveInt(c.PostForm("width"), "width")
+	width, err := h.parseOptionalPositiveInt(c.PostForm("width"), "width")
 	if err != nil {
 		return nil, err
 	}
 
-	height, err := h.parsePositiveInt(c.PostForm("height"), "height")
+	height, err := h.parseOptionalPositiveInt(c.PostForm("height"), "height")
+	if err != nil {
+		return nil, err
+	}
+
+	// 0 turns the palette off; the range is checked by validateProcessingRequest
+	palette, err := h.parseOptionalInt(c.PostForm("palette"), "palette")
 	if err != nil {
 		return nil, err
 	}
 
 	quality := h.parseQuality(c.PostForm("quality"))
 	format := c.PostForm("format")
+	lossless := c.PostForm("lossless") == "true"
 
-	return &models.AdvancedProcessingRequest{
+	req := &models.AdvancedProcessingRequest{
 		Resize: &models.ResizeRequest{
-			Width:   width,
-			Height:  height,
-			Quality: quality,
-			Format:  format,
+			Width:              width,
+			Height:             height,
+			Quality:            quality,
+			Format:             format,
+			Lossless:           lossless,
+			Fit:                c.PostForm("fit"),
+			Background:         c.PostForm("background"),
+			WithoutEnlargement: c.PostForm("without_enlargement") == "true",
+			Filter:             c.PostForm("filter"),
+			SharpenOnDownscale: c.PostForm("sharpen_on_downscale") == "true",
+			Position:           c.PostForm("position"),
 		},
-	}, nil
+		DisableAutoOrient: c.PostForm("disable_auto_orient") == "true",
+		Metadata:          c.PostForm("metadata"),
+		Placeholder:       c.PostForm("placeholder"),
+		Palette:           palette,
+	}
+
+	if err := h.validateProcessingRequest(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (h *ImageHandler) parseComposeParams(c *gin.Context, count int) (*models.ComposeRequest, error) {
+	req := &models.ComposeRequest{
+		Background: c.PostForm("background"),
+		Fit:        c.PostForm("fit"),
+		Position:   c.PostForm("position"),
+		Format:     c.PostForm("format"),
+	}
+
+	for field, target := range map[string]*int{
+		"rows":        &req.Rows,
+		"cols":        &req.Cols,
+		"cell_width":  &req.CellWidth,
+		"cell_height": &req.CellHeight,
+	} {
+		value, err := h.parseOptionalPositiveInt(c.PostForm(field), field)
+		if err != nil {
+			return nil, err
+		}
+		*target = value
+	}
+
+	if gutter := c.PostForm("gutter"); gutter != "" {
+		value, err := strconv.Atoi(gutter)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid gutter: must be a non-negative integer")
+		}
+		req.Gutter = value
+	}
+
+	if c.PostForm("quality") != "" {
+		req.Quality = h.parseQuality(c.PostForm("quality"))
+	}
+
+	if err := h.validateComposeRequest(req, count); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (h *ImageHandler) parseSpriteParams(c *gin.Context, count int) (*models.SpriteRequest, error) {
+	width, err := h.parseOptionalPositiveInt(c.PostForm("width"), "width")
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := h.parseOptionalPositiveInt(c.PostForm("height"), "height")
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.SpriteRequest{
+		Width:       width,
+		Height:      height,
+		Fit:         c.PostForm("fit"),
+		Format:      c.PostForm("format"),
+		CSS:         c.PostForm("css") == "true",
+		ClassPrefix: c.PostForm("class_prefix"),
+	}
+
+	if padding := c.PostForm("padding"); padding != "" {
+		value, err := strconv.Atoi(padding)
+		if err != nil || value < 0 || value > 100 {
+			return nil, fmt.Errorf("invalid padding: must be an integer between 0 and 100")
+		}
+		req.Padding = value
+	}
+
+	if count > services.MaxSpriteImages {
+		return nil, fmt.Errorf("too many images: %d (max %d)", count, services.MaxSpriteImages)
+	}
+
+	switch req.Fit {
+	case "", models.FitCover, models.FitContain, models.FitFill, models.FitInside:
+	default:
+		return nil, fmt.Errorf("invalid fit %q: must be one of cover, contain, fill, inside", req.Fit)
+	}
+
+	switch req.Format {
+	case "", models.FormatPNG, models.FormatWebP, models.FormatGIF:
+	default:
+		return nil, fmt.Errorf("invalid format %q: sprite sheets must be png, webp or gif", req.Format)
+	}
+
+	return req, nil
 }
 
 func (h *ImageHandler) parseAdvancedParams(c *gin.Context) (*models.AdvancedProcessingRequest, error) {
@@ -56,9 +169,406 @@ func (h *ImageHandler) parseAdvancedParams(c *gin.Context) (*models.AdvancedProc
 		return nil, fmt.Errorf("invalid processing request: %v", err)
 	}
 
+	if err := h.validateProcessingRequest(&req); err != nil {
+		return nil, err
+	}
+
+	for _, op := range req.Pipeline() {
+		if op.Watermark == nil || op.Watermark.ImageURL == "" {
+			continue
+		}
+		img, err := h.loadWatermarkImage(c, op.Watermark.ImageURL)
+		if err != nil {
+			return nil, err
+		}
+		op.Watermark.Image = img
+	}
+
 	return &req, nil
 }
 
+func (h *ImageHandler) validateProcessingRequest(req *models.AdvancedProcessingRequest) error {
+	switch req.Metadata {
+	case "", models.MetadataStrip, models.MetadataKeep, models.MetadataCopyright, models.MetadataStripGPS:
+	default:
+		return fmt.Errorf("invalid metadata %q: must be one of strip, keep, copyright, strip-gps", req.Metadata)
+	}
+
+	switch req.Placeholder {
+	case "", models.PlaceholderBlurHash, models.PlaceholderThumbHash, models.PlaceholderLQIP:
+	default:
+		return fmt.Errorf("invalid placeholder %q: must be one of blurhash, thumbhash, lqip", req.Placeholder)
+	}
+
+	if req.Palette < 0 || req.Palette > services.MaxPaletteSize {
+		return fmt.Errorf("palette must be between 0 (off) and %d colours, got %d", services.MaxPaletteSize, req.Palette)
+	}
+
+	if _, err := services.ParseColor(req.Flatten); err != nil {
+		return fmt.Errorf("invalid flatten: %v", err)
+	}
+
+	if len(req.Operations) > 0 && req.HasShorthand() {
+		return fmt.Errorf("use either operations or the shorthand fields, not both")
+	}
+
+	if len(req.Operations) > models.MaxOperations {
+		return fmt.Errorf("too many operations: %d (max %d)", len(req.Operations), models.MaxOperations)
+	}
+
+	for i, op := range req.Operations {
+		if types := op.Types(); len(types) != 1 {
+			return fmt.Errorf("operations[%d] must set exactly one step, got %d", i, len(types))
+		}
+		if err := h.validateOperation(op); err != nil {
+			return fmt.Errorf("operations[%d]: %w", i, err)
+		}
+	}
+
+	if len(req.Operations) == 0 {
+		for _, op := range req.Pipeline() {
+			if err := h.validateOperation(op); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateOperation(op models.Operation) error {
+	switch {
+	case op.Resize != nil:
+		return h.validateResizeRequest(op.Resize)
+	case op.Crop != nil:
+		return h.validateCropRequest(op.Crop)
+	case op.Extend != nil:
+		return h.validateExtendRequest(op.Extend)
+	case op.Rotate != nil:
+		if _, err := services.ParseColor(op.Rotate.Background); err != nil {
+			return fmt.Errorf("invalid rotate background: %v", err)
+		}
+	case op.Flip != "":
+		switch op.Flip {
+		case models.FlipHorizontal, models.FlipVertical, models.FlipBoth:
+		default:
+			return fmt.Errorf("invalid flip %q: must be one of horizontal, vertical, both", op.Flip)
+		}
+	case op.Adjust != nil:
+		return h.validateAdjustRequest(op.Adjust)
+	case op.Blur != 0:
+		if op.Blur < 0 || op.Blur > 100 {
+			return fmt.Errorf("blur must be between 0 and 100, got %g", op.Blur)
+		}
+	case op.Sharpen != nil:
+		return h.validateSharpenRequest(op.Sharpen)
+	case op.Watermark != nil:
+		return h.validateWatermarkRequest(op.Watermark)
+	case op.Border != nil:
+		return h.validateBorderRequest(op.Border)
+	case op.Mask != nil:
+		return h.validateMaskRequest(op.Mask)
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateComposeRequest(req *models.ComposeRequest, count int) error {
+	if count > services.MaxComposeImages {
+		return fmt.Errorf("too many images: %d (max %d)", count, services.MaxComposeImages)
+	}
+
+	if rows, cols := services.GridSize(count, req.Rows, req.Cols); rows*cols < count {
+		return fmt.Errorf("a %dx%d grid cannot hold %d images", rows, cols, count)
+	}
+
+	switch req.Fit {
+	case "", models.FitCover, models.FitContain, models.FitFill, models.FitInside:
+	default:
+		return fmt.Errorf("invalid fit %q: must be one of cover, contain, fill, inside", req.Fit)
+	}
+
+	if req.Position != models.PositionSmart {
+		if err := services.ValidateGravity(req.Position); err != nil {
+			return fmt.Errorf("invalid position: %v", err)
+		}
+	}
+
+	if _, err := services.ParseColor(req.Background); err != nil {
+		return fmt.Errorf("invalid background: %v", err)
+	}
+
+	switch req.Format {
+	case "", models.FormatJPEG, models.FormatPNG, models.FormatWebP, models.FormatGIF, models.FormatBMP, models.FormatTIFF:
+	default:
+		return fmt.Errorf("invalid format %q: must be one of jpeg, png, webp, gif, bmp, tiff", req.Format)
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateCropRequest(req *models.CropRequest) error {
+	if req.Mode != "" && req.Mode != models.CropModeSmart {
+		return fmt.Errorf("invalid crop.mode %q: must be smart", req.Mode)
+	}
+
+	if err := services.ValidateGravity(req.Gravity); err != nil {
+		return fmt.Errorf("invalid crop.gravity: %v", err)
+	}
+
+	if req.AspectRatio != "" {
+		if _, err := services.ParseAspectRatio(req.AspectRatio); err != nil {
+			return fmt.Errorf("invalid crop.aspect_ratio: %v", err)
+		}
+		return nil
+	}
+
+	if req.X.Value < 0 || req.Y.Value < 0 {
+		return fmt.Errorf("crop.x and crop.y must not be negative")
+	}
+
+	if req.Width.Value <= 0 || req.Height.Value <= 0 {
+		return fmt.Errorf("crop requires positive crop.width and crop.height, or crop.aspect_ratio")
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateExtendRequest(req *models.ExtendRequest) error {
+	for side, length := range map[string]models.Length{
+		"top":    req.Top,
+		"right":  req.Right,
+		"bottom": req.Bottom,
+		"left":   req.Left,
+	} {
+		if length.Value < 0 {
+			return fmt.Errorf("extend.%s must not be negative", side)
+		}
+		if length.Percent && length.Value > 100 || !length.Percent && length.Value > services.MaxCanvasSize {
+			return fmt.Errorf("extend.%s is too large: %s", side, length)
+		}
+	}
+
+	if req.Width < 0 || req.Height < 0 || req.Width > services.MaxCanvasSize || req.Height > services.MaxCanvasSize {
+		return fmt.Errorf("extend.width and extend.height must be between 0 and %d", services.MaxCanvasSize)
+	}
+
+	if err := services.ValidateGravity(req.Gravity); err != nil {
+		return fmt.Errorf("invalid extend.gravity: %v", err)
+	}
+
+	switch req.Fill {
+	case "", models.ExtendFillColor, models.ExtendFillBlur:
+	default:
+		return fmt.Errorf("invalid extend.fill %q: must be color or blur", req.Fill)
+	}
+
+	if _, err := services.ParseColor(req.Background); err != nil {
+		return fmt.Errorf("invalid extend.background: %v", err)
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateBorderRequest(req *models.BorderRequest) error {
+	if req.Width < 0 || req.Width > 1000 {
+		return fmt.Errorf("border.width must be between 0 and 1000, got %d", req.Width)
+	}
+
+	if req.Padding < 0 || req.Padding > 1000 {
+		return fmt.Errorf("border.padding must be between 0 and 1000, got %d", req.Padding)
+	}
+
+	if req.ShadowOffset < 0 || req.ShadowOffset > 200 {
+		return fmt.Errorf("border.shadow_offset must be between 0 and 200, got %d", req.ShadowOffset)
+	}
+
+	if req.ShadowBlur < 0 || req.ShadowBlur > 50 {
+		return fmt.Errorf("border.shadow_blur must be between 0 and 50, got %g", req.ShadowBlur)
+	}
+
+	for field, value := range map[string]string{
+		"color":         req.Color,
+		"padding_color": req.PaddingColor,
+		"shadow_color":  req.ShadowColor,
+		"background":    req.Background,
+	} {
+		if _, err := services.ParseColor(value); err != nil {
+			return fmt.Errorf("invalid border.%s: %v", field, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateMaskRequest(req *models.MaskRequest) error {
+	switch req.Shape {
+	case models.MaskRounded, models.MaskCircle, models.MaskEllipse:
+	default:
+		return fmt.Errorf("invalid mask.shape %q: must be one of rounded, circle, ellipse", req.Shape)
+	}
+
+	if req.Radius.Value < 0 {
+		return fmt.Errorf("mask.radius must not be negative")
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateAdjustRequest(req *models.AdjustRequest) error {
+	ranges := []struct {
+		name     string
+		value    float64
+		min, max float64
+	}{
+		{"brightness", req.Brightness, -100, 100},
+		{"contrast", req.Contrast, -100, 100},
+		{"saturation", req.Saturation, -100, 100},
+		{"hue", req.Hue, -180, 180},
+	}
+
+	for _, r := range ranges {
+		if r.value < r.min || r.value > r.max {
+			return fmt.Errorf("adjust.%s must be between %g and %g, got %g", r.name, r.min, r.max, r.value)
+		}
+	}
+
+	if req.Gamma != 0 && (req.Gamma < 0.1 || req.Gamma > 10) {
+		return fmt.Errorf("adjust.gamma must be between 0.1 and 10, got %g", req.Gamma)
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateSharpenRequest(req *models.SharpenRequest) error {
+	if req.Amount < 0 || req.Amount > 10 {
+		return fmt.Errorf("sharpen.amount must be between 0 and 10, got %g", req.Amount)
+	}
+
+	if req.Radius < 0.1 || req.Radius > 10 {
+		return fmt.Errorf("sharpen.radius must be between 0.1 and 10, got %g", req.Radius)
+	}
+
+	if req.Threshold < 0 || req.Threshold > 255 {
+		return fmt.Errorf("sharpen.threshold must be between 0 and 255, got %d", req.Threshold)
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateWatermarkRequest(req *models.WatermarkRequest) error {
+	switch req.Position {
+	case "", models.PositionTopLeft, models.PositionTopCenter, models.PositionTopRight,
+		models.PositionLeftCenter, models.PositionCenter, models.PositionRightCenter,
+		models.PositionBottomLeft, models.PositionBottomCenter, models.PositionBottomRight:
+	default:
+		return fmt.Errorf("invalid watermark.position %q", req.Position)
+	}
+
+	switch req.Mode {
+	case "", models.WatermarkModeSingle, models.WatermarkModeTile:
+	default:
+		return fmt.Errorf("invalid watermark.mode %q: use single or tile", req.Mode)
+	}
+
+	if req.Spacing.Value < 0 {
+		return fmt.Errorf("watermark.spacing must not be negative")
+	}
+
+	if req.Angle != nil && (*req.Angle < -360 || *req.Angle > 360) {
+		return fmt.Errorf("watermark.angle must be between -360 and 360, got %g", *req.Angle)
+	}
+
+	if req.Opacity != nil && (*req.Opacity < 0 || *req.Opacity > 1) {
+		return fmt.Errorf("watermark.opacity must be between 0 and 1, got %g", *req.Opacity)
+	}
+
+	if req.Scale < 0 || req.Scale > 1 {
+		return fmt.Errorf("watermark.scale must be between 0 and 1, got %g", req.Scale)
+	}
+
+	if req.Margin != nil && req.Margin.Value < 0 {
+		return fmt.Errorf("watermark.margin must not be negative")
+	}
+
+	if _, err := h.processor.LoadFont(req.Font); err != nil {
+		return fmt.Errorf("invalid watermark.font: %v", err)
+	}
+
+	if req.FontSize.Value < 0 {
+		return fmt.Errorf("watermark.font_size must not be negative")
+	}
+	if req.FontSize.Percent && req.FontSize.Value > 100 || !req.FontSize.Percent && req.FontSize.Value > services.MaxCanvasSize {
+		return fmt.Errorf("watermark.font_size is too large: %s", req.FontSize)
+	}
+
+	if req.StrokeWidth < 0 || req.StrokeWidth > 20 {
+		return fmt.Errorf("watermark.stroke_width must be between 0 and 20, got %d", req.StrokeWidth)
+	}
+
+	if req.ShadowOffset < 0 || req.ShadowOffset > 50 {
+		return fmt.Errorf("watermark.shadow_offset must be between 0 and 50, got %d", req.ShadowOffset)
+	}
+
+	for field, value := range map[string]string{
+		"color":        req.Color,
+		"stroke_color": req.StrokeColor,
+		"shadow_color": req.ShadowColor,
+	} {
+		if _, err := services.ParseColor(value); err != nil {
+			return fmt.Errorf("invalid watermark.%s: %v", field, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *ImageHandler) validateResizeRequest(req *models.ResizeRequest) error {
+	if req.Width < 0 || req.Height < 0 {
+		return fmt.Errorf("width and height must be positive integers")
+	}
+
+	if req.Width == 0 && req.Height == 0 {
+		return fmt.Errorf("width or height is required")
+	}
+
+	switch req.Fit {
+	case "", models.FitCover, models.FitContain, models.FitFill, models.FitInside, models.FitOutside:
+	default:
+		return fmt.Errorf("invalid fit %q: must be one of cover, contain, fill, inside, outside", req.Fit)
+	}
+
+	if _, err := services.ParseColor(req.Background); err != nil {
+		return fmt.Errorf("invalid background: %v", err)
+	}
+
+	if _, err := services.LookupFilter(req.Filter); err != nil {
+		return fmt.Errorf("invalid filter: %v", err)
+	}
+
+	if req.Position != models.PositionSmart {
+		if err := services.ValidateGravity(req.Position); err != nil {
+			return fmt.Errorf("invalid position: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// validateBatchResizeRequest rejects the per-image extras that the batch
+// response has no field for
+func (h *ImageHandler) validateBatchResizeRequest(req *models.AdvancedProcessingRequest) error {
+	if req.Placeholder != "" {
+		return fmt.Errorf("placeholder is not supported for batch resize")
+	}
+
+	if req.Palette > 0 {
+		return fmt.Errorf("palette is not supported for batch resize")
+	}
+
+	return nil
+}
+
 func (h *ImageHandler) parseMultipartFiles(c *gin.Context) ([]*multipart.FileHeader, error) {
 	if err := c.Request.ParseMultipartForm(h.config.Storage.MaxFileSize * 10); err != nil {
 		return nil, fmt.Errorf("failed to parse form data: %v", err)
@@ -89,6 +599,27 @@ func (h *ImageHandler) parsePositiveInt(value, fieldName string) (int, error) {
 	return num, nil
 }
 
+func (h *ImageHandler) parseOptionalPositiveInt(value, fieldName string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return h.parsePositiveInt(value, fieldName)
+}
+
+func (h *ImageHandler) parseOptionalInt(value, fieldName string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be a number", fieldName)
+	}
+
+	return num, nil
+}
+
 func (h *ImageHandler) parseQuality(value string) int {
 	if value == "" {
 		return defaultQuality
@@ -108,6 +639,51 @@ func (h *ImageHandler) getUploadedFile(c *gin.Context, paramKey string) (multipa
 	return c.Request.FormFile(paramKey)
 }
 
+// loadWatermarkImage resolves a watermark image_url from the watermark
+// directory (file://), another multipart part (upload://) or storage (asset://)
+func (h *ImageHandler) loadWatermarkImage(c *gin.Context, source string) (image.Image, error) {
+	switch {
+	case strings.HasPrefix(source, models.WatermarkSourceUpload):
+		field := strings.TrimPrefix(source, models.WatermarkSourceUpload)
+		file, _, err := h.getUploadedFile(c, field)
+		if err != nil {
+			return nil, fmt.Errorf("watermark upload %q not provided", field)
+		}
+		defer file.Close()
+
+		return h.processor.DecodeWatermark(file)
+
+	case strings.HasPrefix(source, models.WatermarkSourceFile):
+		name := strings.TrimPrefix(source, models.WatermarkSourceFile)
+		// Clean against the root so the path cannot escape the watermark directory
+		path := filepath.Join(h.config.Storage.WatermarkDir, filepath.Clean("/"+name))
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("watermark file %q not found", name)
+		}
+		defer file.Close()
+
+		return h.processor.DecodeWatermark(file)
+
+	case strings.HasPrefix(source, models.WatermarkSourceAsset):
+		if h.storage == nil {
+			return nil, fmt.Errorf("watermark assets require storage to be configured")
+		}
+
+		key := strings.TrimPrefix(source, models.WatermarkSourceAsset)
+		data, err := h.storage.Download(c.Request.Context(), key)
+		if err != nil {
+			h.logger.Warn("Failed to load watermark asset", zap.String("key", key), zap.Error(err))
+			return nil, fmt.Errorf("watermark asset %q not found", key)
+		}
+
+		return h.processor.DecodeWatermark(bytes.NewReader(data))
+
+	default:
+		return nil, fmt.Errorf("unsupported watermark image_url %q: use file://, upload:// or asset://", source)
+	}
+}
+
 func (h *ImageHandler) openFiles(files []*multipart.FileHeader) ([]multipart.File, error) {
 	var openedFiles []multipart.File
 
@@ -161,23 +737,44 @@ func (h *ImageHandler) respondWithURL(
 		Format:  format,
 	}
 
-	if req != nil && req.Resize != nil {
-		size.Width = req.Resize.Width
-		size.Height = req.Resize.Height
-		size.Quality = req.Resize.Quality
-		size.Format = req.Resize.Format
+	// Width, height and format always come from the processed image because
+	// aspect-preserving fit modes may not match the requested size and
+	// transparent results may be encoded as PNG.
+	if req != nil {
+		if output := req.Output(); output != nil {
+			size.Quality = output.Quality
+		}
+	}
+
+	processed := models.ProcessedImage{
+		ID:          uuid.New().String(),
+		OriginalURL: header.Filename,
+		URL:         imageURL,
+		FileSize:    int64(buffer.Len()),
+		ProcessedAt: time.Now(),
+		Size:        size,
+	}
+
+	// Placeholders are only computed on request to keep the default path cheap
+	if req != nil && req.Placeholder != "" {
+		placeholder, err := h.processor.Placeholder(img, req.Placeholder)
+		if err != nil {
+			h.logger.Warn("Failed to generate placeholder", zap.Error(err))
+		}
+		processed.Placeholder = placeholder
+	}
+
+	if req != nil && req.Palette > 0 {
+		palette, err := h.processor.Palette(img, req.Palette)
+		if err != nil {
+			h.logger.Warn("Failed to extract palette", zap.Error(err))
+		}
+		processed.Palette = palette
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Data: models.ProcessedImage{
-			ID:          uuid.New().String(),
-			OriginalURL: header.Filename,
-			URL:         imageURL,
-			FileSize:    int64(buffer.Len()),
-			ProcessedAt: time.Now(),
-			Size:        size,
-		},
+		Data:    processed,
 	})
 }
 
@@ -230,7 +827,7 @@ func (h *ImageHandler) generateNewFilename(originalFilename, format string) stri
 	return strings.TrimSuffix(originalFilename, filepath.Ext(originalFilename)) + ext
 }
 
-func (h *ImageHandler) buildBatchResponse(ctx context.Context, images []models.BatchImage, files []*multipart.FileHeader, format string) models.BatchResponse {
+func (h *ImageHandler) buildBatchResponse(ctx context.Context, images []models.BatchImage, files []*multipart.FileHeader) models.BatchResponse {
 	var batchResponse models.BatchResponse
 
 	for i, img := range images {
@@ -238,7 +835,7 @@ func (h *ImageHandler) buildBatchResponse(ctx context.Context, images []models.B
 			continue
 		}
 
-		url := h.uploadToStorage(ctx, img.Buffer, files[i], format)
+		url := h.uploadToStorage(ctx, img.Buffer, files[i], img.Format)
 		batchResponse.Images = append(batchResponse.Images, models.ImageResponse{
 			URL:         url,
 			FileSize:    img.FileSize,
@@ -249,15 +846,39 @@ func (h *ImageHandler) buildBatchResponse(ctx context.Context, images []models.B
 	return batchResponse
 }
 
+func (h *ImageHandler) buildSpriteResponse(ctx context.Context, sheet *services.SpriteSheet, req *models.SpriteRequest) models.SpriteResponse {
+	url := h.uploadBuffer(ctx, sheet.Buffer, spriteFilename+"."+sheet.Format, sheet.Format)
+
+	response := models.SpriteResponse{
+		URL:         url,
+		Width:       sheet.Image.Bounds().Dx(),
+		Height:      sheet.Image.Bounds().Dy(),
+		Format:      sheet.Format,
+		FileSize:    int64(sheet.Buffer.Len()),
+		Frames:      sheet.Frames,
+		Errors:      sheet.Errors,
+		ProcessedAt: time.Now(),
+	}
+
+	if req.CSS {
+		response.CSS = services.SpriteCSS(url, req.ClassPrefix, sheet.Frames)
+	}
+
+	return response
+}
+
 // === STORAGE OPERATIONS ===
 
 func (h *ImageHandler) uploadToStorage(ctx context.Context, buffer *bytes.Buffer, header *multipart.FileHeader, format string) string {
+	return h.uploadBuffer(ctx, buffer, h.generateNewFilename(header.Filename, format), format)
+}
+
+func (h *ImageHandler) uploadBuffer(ctx context.Context, buffer *bytes.Buffer, filename, format string) string {
 	if h.storage == nil {
 		return ""
 	}
 
-	newFilename := h.generateNewFilename(header.Filename, format)
-	url, err := h.storage.Upload(ctx, buffer, newFilename, "image/"+format)
+	url, err := h.storage.Upload(ctx, buffer, filename, "image/"+format)
 	if err != nil {
 		h.logger.Warn("Failed to upload to Storage", zap.Error(err))
 		return ""
@@ -47,6 +47,9 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			images.POST("/resize", r.imageHandler.ResizeImage)
 			images.POST("/batch/resize", r.imageHandler.BatchResize)
 			images.POST("/process", r.imageHandler.AdvancedProcess)
+			images.POST("/compose", r.imageHandler.ComposeImages)
+			images.POST("/sprite", r.imageHandler.SpriteSheet)
+			images.POST("/palette", r.imageHandler.ExtractPalette)
 		}
 	}
 
@@ -0,0 +1,9 @@
+package models
+
+type AdjustRequest struct {
+	Brightness float64 `json:"brightness,omitempty" binding:"min=-100,max=100"`
+	Contrast   float64 `json:"contrast,omitempty" binding:"min=-100,max=100"`
+	Gamma      float64 `json:"gamma,omitempty" binding:"omitempty,min=0.1,max=10"`
+	Saturation float64 `json:"saturation,omitempty" binding:"min=-100,max=100"`
+	Hue        float64 `json:"hue,omitempty" binding:"min=-180,max=180"`
+}
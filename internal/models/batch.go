@@ -7,6 +7,7 @@ import (
 
 type BatchImage struct {
 	Buffer   *bytes.Buffer
+	Format   string // encoded format, which may differ from the requested one
 	Error    string
 	FileSize int64
 }
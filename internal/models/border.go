@@ -0,0 +1,12 @@
+package models
+
+type BorderRequest struct {
+	Width        int     `json:"width" binding:"min=0,max=1000"`
+	Color        string  `json:"color,omitempty"`
+	Padding      int     `json:"padding,omitempty" binding:"min=0,max=1000"`
+	PaddingColor string  `json:"padding_color,omitempty"`
+	ShadowColor  string  `json:"shadow_color,omitempty"`
+	ShadowOffset int     `json:"shadow_offset,omitempty" binding:"min=0,max=200"`
+	ShadowBlur   float64 `json:"shadow_blur,omitempty" binding:"min=0,max=50"`
+	Background   string  `json:"background,omitempty"`
+}
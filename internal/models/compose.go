@@ -0,0 +1,14 @@
+package models
+
+type ComposeRequest struct {
+	Rows       int    `json:"rows,omitempty" binding:"min=0"`
+	Cols       int    `json:"cols,omitempty" binding:"min=0"`
+	CellWidth  int    `json:"cell_width,omitempty" binding:"min=0"`
+	CellHeight int    `json:"cell_height,omitempty" binding:"min=0"`
+	Gutter     int    `json:"gutter,omitempty" binding:"min=0"`
+	Background string `json:"background,omitempty"`
+	Fit        string `json:"fit,omitempty" binding:"omitempty,oneof=cover contain fill inside"`
+	Position   string `json:"position,omitempty"`
+	Format     string `json:"format,omitempty" binding:"omitempty,oneof=jpeg png webp gif bmp tiff"`
+	Quality    int    `json:"quality,omitempty" binding:"min=1,max=100"`
+}
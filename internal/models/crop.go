@@ -1,8 +1,27 @@
 package models
 
 type CropRequest struct {
-	X      int `json:"x" binding:"min=0"`
-	Y      int `json:"y" binding:"min=0"`
-	Width  int `json:"width" binding:"required,min=1"`
-	Height int `json:"height" binding:"required,min=1"`
+	X           Length `json:"x"`
+	Y           Length `json:"y"`
+	Width       Length `json:"width" binding:"required_without=AspectRatio"`
+	Height      Length `json:"height" binding:"required_without=AspectRatio"`
+	Mode        string `json:"mode,omitempty" binding:"omitempty,oneof=smart"`
+	AspectRatio string `json:"aspect_ratio,omitempty"`
+	Gravity     string `json:"gravity,omitempty" binding:"omitempty,oneof=center north south east west north-east north-west south-east south-west"`
 }
+
+const (
+	CropModeSmart = "smart"
+)
+
+const (
+	GravityCenter    = "center"
+	GravityNorth     = "north"
+	GravitySouth     = "south"
+	GravityEast      = "east"
+	GravityWest      = "west"
+	GravityNorthEast = "north-east"
+	GravityNorthWest = "north-west"
+	GravitySouthEast = "south-east"
+	GravitySouthWest = "south-west"
+)
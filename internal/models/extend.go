@@ -0,0 +1,18 @@
+package models
+
+type ExtendRequest struct {
+	Top        Length `json:"top"`
+	Right      Length `json:"right"`
+	Bottom     Length `json:"bottom"`
+	Left       Length `json:"left"`
+	Width      int    `json:"width,omitempty" binding:"min=0"`
+	Height     int    `json:"height,omitempty" binding:"min=0"`
+	Gravity    string `json:"gravity,omitempty" binding:"omitempty,oneof=center north south east west north-east north-west south-east south-west"`
+	Fill       string `json:"fill,omitempty" binding:"omitempty,oneof=color blur"`
+	Background string `json:"background,omitempty"`
+}
+
+const (
+	ExtendFillColor = "color"
+	ExtendFillBlur  = "blur"
+)
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Length is a distance in pixels or a percentage of the image dimension it is
+// resolved against. It unmarshals from a JSON number (120) or string ("10%").
+type Length struct {
+	Value   float64
+	Percent bool
+}
+
+// Resolve converts the length to pixels relative to total
+func (l Length) Resolve(total int) int {
+	if l.Percent {
+		return int(math.Round(l.Value * float64(total) / 100))
+	}
+	return int(math.Round(l.Value))
+}
+
+func (l Length) IsZero() bool {
+	return l.Value == 0
+}
+
+func (l Length) String() string {
+	value := strconv.FormatFloat(l.Value, 'f', -1, 64)
+	if l.Percent {
+		return value + "%"
+	}
+	return value
+}
+
+func (l Length) MarshalJSON() ([]byte, error) {
+	if l.Percent {
+		return json.Marshal(l.String())
+	}
+	return json.Marshal(l.Value)
+}
+
+func (l *Length) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*l = Length{Value: number}
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("length must be a number or a percentage string")
+	}
+
+	text = strings.TrimSpace(text)
+	percent := strings.HasSuffix(text, "%")
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "%"), "px")
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return fmt.Errorf("invalid length %q", text)
+	}
+
+	*l = Length{Value: value, Percent: percent}
+	return nil
+}
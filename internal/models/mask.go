@@ -0,0 +1,12 @@
+package models
+
+type MaskRequest struct {
+	Shape  string `json:"shape" binding:"required,oneof=rounded circle ellipse"`
+	Radius Length `json:"radius"`
+}
+
+const (
+	MaskRounded = "rounded"
+	MaskCircle  = "circle"
+	MaskEllipse = "ellipse"
+)
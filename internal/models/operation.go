@@ -0,0 +1,74 @@
+package models
+
+// Operation is one step of an ordered processing pipeline. Exactly one field
+// is set and its JSON key names the step, e.g. {"resize": {"width": 300}} or
+// {"blur": 2}, so steps use the same shape as the shorthand fields.
+type Operation struct {
+	Crop      *CropRequest      `json:"crop,omitempty"`
+	Resize    *ResizeRequest    `json:"resize,omitempty"`
+	Extend    *ExtendRequest    `json:"extend,omitempty"`
+	Rotate    *RotateRequest    `json:"rotate,omitempty"`
+	Flip      string            `json:"flip,omitempty" binding:"omitempty,oneof=horizontal vertical both"`
+	Adjust    *AdjustRequest    `json:"adjust,omitempty"`
+	Blur      float64           `json:"blur,omitempty" binding:"min=0,max=100"`
+	Sharpen   *SharpenRequest   `json:"sharpen,omitempty"`
+	Watermark *WatermarkRequest `json:"watermark,omitempty"`
+	Border    *BorderRequest    `json:"border,omitempty"`
+	Mask      *MaskRequest      `json:"mask,omitempty"`
+}
+
+const (
+	OperationCrop      = "crop"
+	OperationResize    = "resize"
+	OperationExtend    = "extend"
+	OperationRotate    = "rotate"
+	OperationFlip      = "flip"
+	OperationAdjust    = "adjust"
+	OperationBlur      = "blur"
+	OperationSharpen   = "sharpen"
+	OperationWatermark = "watermark"
+	OperationBorder    = "border"
+	OperationMask      = "mask"
+)
+
+// MaxOperations limits the length of an operations pipeline
+const MaxOperations = 20
+
+// Types returns the names of the steps set on the operation
+func (o Operation) Types() []string {
+	var types []string
+	if o.Crop != nil {
+		types = append(types, OperationCrop)
+	}
+	if o.Resize != nil {
+		types = append(types, OperationResize)
+	}
+	if o.Extend != nil {
+		types = append(types, OperationExtend)
+	}
+	if o.Rotate != nil {
+		types = append(types, OperationRotate)
+	}
+	if o.Flip != "" {
+		types = append(types, OperationFlip)
+	}
+	if o.Adjust != nil {
+		types = append(types, OperationAdjust)
+	}
+	if o.Blur != 0 {
+		types = append(types, OperationBlur)
+	}
+	if o.Sharpen != nil {
+		types = append(types, OperationSharpen)
+	}
+	if o.Watermark != nil {
+		types = append(types, OperationWatermark)
+	}
+	if o.Border != nil {
+		types = append(types, OperationBorder)
+	}
+	if o.Mask != nil {
+		types = append(types, OperationMask)
+	}
+	return types
+}
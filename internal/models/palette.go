@@ -0,0 +1,14 @@
+package models
+
+type PaletteColor struct {
+	Hex        string  `json:"hex"`
+	RGB        [3]int  `json:"rgb"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Palette lists the main colours of an image by share of its opaque pixels;
+// Dominant is the most common one
+type Palette struct {
+	Dominant PaletteColor   `json:"dominant"`
+	Colors   []PaletteColor `json:"colors"`
+}
@@ -3,10 +3,25 @@ package models
 import "time"
 
 type ProcessedImage struct {
-	ID          string     `json:"id"`
-	OriginalURL string     `json:"original_url"`
-	ProcessedAt time.Time  `json:"processed_at"`
-	Size        ResizeSize `json:"size"`
-	URL         string     `json:"url"`
-	FileSize    int64      `json:"file_size"`
+	ID          string       `json:"id"`
+	OriginalURL string       `json:"original_url"`
+	ProcessedAt time.Time    `json:"processed_at"`
+	Size        ResizeSize   `json:"size"`
+	URL         string       `json:"url"`
+	FileSize    int64        `json:"file_size"`
+	Placeholder *Placeholder `json:"placeholder,omitempty"`
+	Palette     *Palette     `json:"palette,omitempty"`
 }
+
+// Placeholder holds the optional preview data returned with a processed image
+type Placeholder struct {
+	BlurHash  string `json:"blurhash,omitempty"`
+	ThumbHash string `json:"thumbhash,omitempty"`
+	LQIP      string `json:"lqip,omitempty"`
+}
+
+const (
+	PlaceholderBlurHash  = "blurhash"
+	PlaceholderThumbHash = "thumbhash"
+	PlaceholderLQIP      = "lqip"
+)
@@ -1,7 +1,90 @@
 package models
 
 type AdvancedProcessingRequest struct {
-	Resize    *ResizeRequest    `json:"resize,omitempty"`
-	Crop      *CropRequest      `json:"crop,omitempty"`
-	Watermark *WatermarkRequest `json:"watermark,omitempty"`
+	Resize            *ResizeRequest    `json:"resize,omitempty"`
+	Crop              *CropRequest      `json:"crop,omitempty"`
+	Extend            *ExtendRequest    `json:"extend,omitempty"`
+	Watermark         *WatermarkRequest `json:"watermark,omitempty"`
+	Rotate            *RotateRequest    `json:"rotate,omitempty"`
+	Flip              string            `json:"flip,omitempty" binding:"omitempty,oneof=horizontal vertical both"`
+	Adjust            *AdjustRequest    `json:"adjust,omitempty"`
+	Blur              float64           `json:"blur,omitempty" binding:"min=0,max=100"`
+	Sharpen           *SharpenRequest   `json:"sharpen,omitempty"`
+	Border            *BorderRequest    `json:"border,omitempty"`
+	Mask              *MaskRequest      `json:"mask,omitempty"`
+	DisableAutoOrient bool              `json:"disable_auto_orient,omitempty"`
+	Metadata          string            `json:"metadata,omitempty" binding:"omitempty,oneof=strip keep copyright strip-gps"`
+	Operations        []Operation       `json:"operations,omitempty" binding:"omitempty,max=20,dive"`
+	Flatten           string            `json:"flatten,omitempty"`
+	Placeholder       string            `json:"placeholder,omitempty" binding:"omitempty,oneof=blurhash thumbhash lqip"`
+	Palette           int               `json:"palette,omitempty" binding:"min=0,max=16"`
 }
+
+// Pipeline returns the steps to run in order: the operations array when set,
+// otherwise the shorthand fields in their fixed order
+func (r *AdvancedProcessingRequest) Pipeline() []Operation {
+	if len(r.Operations) > 0 {
+		return r.Operations
+	}
+
+	var ops []Operation
+	if r.Crop != nil {
+		ops = append(ops, Operation{Crop: r.Crop})
+	}
+	if r.Rotate != nil {
+		ops = append(ops, Operation{Rotate: r.Rotate})
+	}
+	if r.Flip != "" {
+		ops = append(ops, Operation{Flip: r.Flip})
+	}
+	if r.Resize != nil {
+		ops = append(ops, Operation{Resize: r.Resize})
+	}
+	if r.Extend != nil {
+		ops = append(ops, Operation{Extend: r.Extend})
+	}
+	if r.Adjust != nil {
+		ops = append(ops, Operation{Adjust: r.Adjust})
+	}
+	if r.Blur != 0 {
+		ops = append(ops, Operation{Blur: r.Blur})
+	}
+	if r.Sharpen != nil {
+		ops = append(ops, Operation{Sharpen: r.Sharpen})
+	}
+	if r.Watermark != nil {
+		ops = append(ops, Operation{Watermark: r.Watermark})
+	}
+	if r.Border != nil {
+		ops = append(ops, Operation{Border: r.Border})
+	}
+	if r.Mask != nil {
+		ops = append(ops, Operation{Mask: r.Mask})
+	}
+	return ops
+}
+
+// HasShorthand reports whether any shorthand transformation field is set
+func (r *AdvancedProcessingRequest) HasShorthand() bool {
+	return r.Crop != nil || r.Rotate != nil || r.Flip != "" || r.Resize != nil || r.Extend != nil ||
+		r.Adjust != nil || r.Blur != 0 || r.Sharpen != nil || r.Watermark != nil || r.Border != nil || r.Mask != nil
+}
+
+// Output returns the last resize step, which carries the output format,
+// quality and lossless settings, or nil when the pipeline has none
+func (r *AdvancedProcessingRequest) Output() *ResizeRequest {
+	ops := r.Pipeline()
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].Resize != nil {
+			return ops[i].Resize
+		}
+	}
+	return nil
+}
+
+const (
+	MetadataStrip     = "strip"
+	MetadataKeep      = "keep"
+	MetadataCopyright = "copyright"
+	MetadataStripGPS  = "strip-gps"
+)
@@ -1,10 +1,17 @@
 package models
 
 type ResizeRequest struct {
-	Width   int    `json:"width" binding:"required,min=1"`
-	Height  int    `json:"height" binding:"required,min=1"`
-	Quality int    `json:"quality" binding:"min=1,max=100"`
-	Format  string `json:"format" binding:"omitempty,oneof=jpeg png webp"`
+	Width              int    `json:"width,omitempty" binding:"required_without=Height,omitempty,min=1"`
+	Height             int    `json:"height,omitempty" binding:"required_without=Width,omitempty,min=1"`
+	Quality            int    `json:"quality" binding:"min=1,max=100"`
+	Format             string `json:"format" binding:"omitempty,oneof=jpeg png webp gif bmp tiff"`
+	Lossless           bool   `json:"lossless,omitempty"`
+	Fit                string `json:"fit,omitempty" binding:"omitempty,oneof=cover contain fill inside outside"`
+	Background         string `json:"background,omitempty"`
+	WithoutEnlargement bool   `json:"without_enlargement,omitempty"`
+	Filter             string `json:"filter,omitempty"`
+	SharpenOnDownscale bool   `json:"sharpen_on_downscale,omitempty"`
+	Position           string `json:"position,omitempty" binding:"omitempty,oneof=smart center north south east west north-east north-west south-east south-west"`
 }
 
 type ResizeSize struct {
@@ -12,11 +19,25 @@ type ResizeSize struct {
 	Width   int    `json:"width" binding:"required,min=1"`
 	Height  int    `json:"height" binding:"required,min=1"`
 	Quality int    `json:"quality" binding:"min=1,max=100"`
-	Format  string `json:"format" binding:"omitempty,oneof=jpeg png webp"`
+	Format  string `json:"format" binding:"omitempty,oneof=jpeg png webp gif bmp tiff"`
 }
 
 const (
 	FormatJPEG = "jpeg"
 	FormatPNG  = "png"
 	FormatWebP = "webp"
+	FormatGIF  = "gif"
+	FormatBMP  = "bmp"
+	FormatTIFF = "tiff"
 )
+
+const (
+	FitCover   = "cover"
+	FitContain = "contain"
+	FitFill    = "fill"
+	FitInside  = "inside"
+	FitOutside = "outside"
+)
+
+// PositionSmart selects smart cropping for fit=cover; any gravity is also a valid position
+const PositionSmart = "smart"
@@ -0,0 +1,12 @@
+package models
+
+type RotateRequest struct {
+	Angle      float64 `json:"angle"`
+	Background string  `json:"background,omitempty"`
+}
+
+const (
+	FlipHorizontal = "horizontal"
+	FlipVertical   = "vertical"
+	FlipBoth       = "both"
+)
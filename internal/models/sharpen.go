@@ -0,0 +1,7 @@
+package models
+
+type SharpenRequest struct {
+	Amount    float64 `json:"amount" binding:"min=0,max=10"`
+	Radius    float64 `json:"radius" binding:"min=0.1,max=10"`
+	Threshold int     `json:"threshold" binding:"min=0,max=255"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type SpriteRequest struct {
+	Width       int    `json:"width,omitempty" binding:"min=0"`
+	Height      int    `json:"height,omitempty" binding:"min=0"`
+	Fit         string `json:"fit,omitempty" binding:"omitempty,oneof=cover contain fill inside"`
+	Padding     int    `json:"padding,omitempty" binding:"min=0,max=100"`
+	Format      string `json:"format,omitempty" binding:"omitempty,oneof=png webp gif"`
+	CSS         bool   `json:"css,omitempty"`
+	ClassPrefix string `json:"class_prefix,omitempty"`
+}
+
+// SpriteFrame is the rectangle of one source image inside the sheet
+type SpriteFrame struct {
+	Name   string `json:"name"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type SpriteResponse struct {
+	URL         string        `json:"url"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	Format      string        `json:"format"`
+	FileSize    int64         `json:"file_size"`
+	Frames      []SpriteFrame `json:"frames"`
+	CSS         string        `json:"css,omitempty"`
+	Errors      []string      `json:"errors,omitempty"`
+	ProcessedAt time.Time     `json:"processed_at"`
+}
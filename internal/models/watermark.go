@@ -1,8 +1,54 @@
 package models
 
+import "image"
+
 type WatermarkRequest struct {
-	Text     string  `json:"text,omitempty"`
-	ImageURL string  `json:"image_url,omitempty"`
-	Position string  `json:"position" binding:"required,oneof=top-left top-right bottom-left bottom-right center"`
-	Opacity  float64 `json:"opacity" binding:"min=0,max=1"`
+	Text     string   `json:"text,omitempty"`
+	ImageURL string   `json:"image_url,omitempty"`
+	Position string   `json:"position" binding:"required,oneof=top-left top-center top-right left-center center right-center bottom-left bottom-center bottom-right"`
+	Opacity  *float64 `json:"opacity,omitempty" binding:"omitempty,min=0,max=1"`
+	OffsetX  Length   `json:"offset_x"`
+	OffsetY  Length   `json:"offset_y"`
+	Scale    float64  `json:"scale,omitempty" binding:"omitempty,gt=0,max=1"`
+	Margin   *Length  `json:"margin,omitempty"`
+
+	Mode    string   `json:"mode,omitempty" binding:"omitempty,oneof=single tile"`
+	Spacing Length   `json:"spacing"`
+	Angle   *float64 `json:"angle,omitempty" binding:"omitempty,min=-360,max=360"`
+
+	Font         string `json:"font,omitempty"`
+	FontSize     Length `json:"font_size"`
+	Color        string `json:"color,omitempty"`
+	StrokeWidth  int    `json:"stroke_width,omitempty" binding:"min=0,max=20"`
+	StrokeColor  string `json:"stroke_color,omitempty"`
+	ShadowColor  string `json:"shadow_color,omitempty"`
+	ShadowOffset int    `json:"shadow_offset,omitempty" binding:"min=0,max=50"`
+
+	// Image is the decoded watermark resolved from ImageURL by the handler
+	Image image.Image `json:"-"`
 }
+
+// Watermark image sources accepted in ImageURL
+const (
+	WatermarkSourceFile   = "file://"
+	WatermarkSourceUpload = "upload://"
+	WatermarkSourceAsset  = "asset://"
+)
+
+// Watermark modes
+const (
+	WatermarkModeSingle = "single"
+	WatermarkModeTile   = "tile"
+)
+
+const (
+	PositionTopLeft      = "top-left"
+	PositionTopCenter    = "top-center"
+	PositionTopRight     = "top-right"
+	PositionLeftCenter   = "left-center"
+	PositionCenter       = "center"
+	PositionRightCenter  = "right-center"
+	PositionBottomLeft   = "bottom-left"
+	PositionBottomCenter = "bottom-center"
+	PositionBottomRight  = "bottom-right"
+)
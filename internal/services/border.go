@@ -0,0 +1,76 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const DefaultBorderColor = "#000000"
+
+// borderImage frames the image: an optional inner padding, a solid border of
+// the given width and, when a shadow colour is set, a blurred drop shadow on
+// a background canvas enlarged to hold it
+func (p *ImageProcessor) borderImage(img image.Image, req *models.BorderRequest) image.Image {
+	bounds := img.Bounds()
+	width, padding := max(0, req.Width), max(0, req.Padding)
+
+	// Thin the border and padding proportionally when the frame would push
+	// the canvas past MaxCanvasSize; extend scales its padding the same way
+	room := max(0, MaxCanvasSize-max(bounds.Dx(), bounds.Dy())) / 2
+	width, padding = fitPadding(width, padding, room)
+	inset := width + padding
+
+	borderColor := req.Color
+	if borderColor == "" {
+		borderColor = DefaultBorderColor
+	}
+
+	framed := imaging.New(bounds.Dx()+2*inset, bounds.Dy()+2*inset, colorOrDefault(borderColor))
+	if padding > 0 {
+		mat := imaging.New(bounds.Dx()+2*padding, bounds.Dy()+2*padding, colorOrDefault(req.PaddingColor))
+		framed = imaging.Paste(framed, mat, image.Pt(width, width))
+	}
+	framed = imaging.Overlay(framed, img, image.Pt(inset, inset), 1.0)
+
+	if req.ShadowColor == "" {
+		return framed
+	}
+
+	size := framed.Bounds().Size()
+	offset := req.ShadowOffset
+	if offset <= 0 {
+		offset = max(1, min(size.X, size.Y)/50)
+	}
+	blur := req.ShadowBlur
+	if blur <= 0 {
+		blur = float64(offset) / 2
+	}
+
+	// The blur spreads about three sigma past the shadow's edge. Shrink the
+	// shadow when it would not fit within MaxCanvasSize, and drop it if
+	// there is no room at all.
+	spread := int(math.Ceil(3 * blur))
+	room = MaxCanvasSize - max(size.X, size.Y)
+	if extra := offset + 2*spread; extra > room {
+		if room < 3 {
+			return framed
+		}
+		scale := float64(room) / float64(extra)
+		offset = max(1, int(float64(offset)*scale))
+		spread = (room - offset) / 2
+		blur = min(blur*scale, float64(spread)/3)
+	}
+	canvasSize := size.Add(image.Pt(offset+2*spread, offset+2*spread))
+
+	shadow := imaging.New(canvasSize.X, canvasSize.Y, color.Transparent)
+	shadow = imaging.Paste(shadow, imaging.New(size.X, size.Y, colorOrDefault(req.ShadowColor)), image.Pt(spread+offset, spread+offset))
+	shadow = imaging.Blur(shadow, blur)
+
+	canvas := imaging.New(canvasSize.X, canvasSize.Y, colorOrDefault(req.Background))
+	canvas = imaging.Overlay(canvas, shadow, image.Point{}, 1.0)
+	return imaging.Overlay(canvas, framed, image.Pt(spread, spread), 1.0)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"image"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestBorderImageFitsMaxCanvasSize(t *testing.T) {
+	p := NewImageProcessor()
+
+	tests := []struct {
+		name   string
+		width  int
+		req    models.BorderRequest
+		inset  bool
+		shadow bool
+	}{
+		{"small frame", 100, models.BorderRequest{Width: 10, Padding: 5, ShadowColor: "#00000080"}, true, true},
+		{"frame too wide", MaxCanvasSize - 10, models.BorderRequest{Width: 1000, Padding: 1000}, true, false},
+		{"shadow too wide", MaxCanvasSize - 100, models.BorderRequest{Width: 10, ShadowColor: "#000000", ShadowOffset: 200, ShadowBlur: 50}, true, true},
+		{"no room left", MaxCanvasSize, models.BorderRequest{Width: 20, ShadowColor: "#000000"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, 4))
+			out := p.borderImage(img, &tt.req)
+
+			size := out.Bounds().Size()
+			if size.X > MaxCanvasSize || size.Y > MaxCanvasSize {
+				t.Fatalf("bordered image %v exceeds %d", size, MaxCanvasSize)
+			}
+			if grew := size.X > tt.width; grew != tt.inset {
+				t.Errorf("bordered width %d from %d, want frame drawn: %t", size.X, tt.width, tt.inset)
+			}
+			if tt.shadow && size.X-tt.width <= 2*(tt.req.Width+tt.req.Padding) {
+				t.Errorf("bordered width %d from %d leaves no room for the shadow", size.X, tt.width)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	xwebp "golang.org/x/image/webp"
+)
+
+// memFile adapts an in-memory image to multipart.File
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error { return nil }
+
+// quadrantColors are the colours of the test image quadrants, in the order
+// top-left, top-right, bottom-left, bottom-right. They are all in the Plan 9
+// palette so that GIF encoding keeps them exactly.
+var quadrantColors = []color.NRGBA{
+	{255, 0, 0, 255},
+	{0, 255, 0, 255},
+	{0, 0, 255, 255},
+	{255, 255, 255, 255},
+}
+
+func quadrantImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := 0
+			if x >= width/2 {
+				i++
+			}
+			if y >= height/2 {
+				i += 2
+			}
+			img.SetNRGBA(x, y, quadrantColors[i])
+		}
+	}
+	return img
+}
+
+// checkQuadrants compares the centre pixel of each quadrant with tolerance per channel
+func checkQuadrants(t *testing.T, img image.Image, width, height, tolerance int) {
+	t.Helper()
+
+	if size := img.Bounds().Size(); size != image.Pt(width, height) {
+		t.Fatalf("decoded size = %v, want %dx%d", size, width, height)
+	}
+
+	points := []image.Point{
+		{width / 4, height / 4},
+		{3 * width / 4, height / 4},
+		{width / 4, 3 * height / 4},
+		{3 * width / 4, 3 * height / 4},
+	}
+	for i, pt := range points {
+		got := color.NRGBAModel.Convert(img.At(img.Bounds().Min.X+pt.X, img.Bounds().Min.Y+pt.Y)).(color.NRGBA)
+		want := quadrantColors[i]
+		for _, diff := range []int{
+			int(got.R) - int(want.R),
+			int(got.G) - int(want.G),
+			int(got.B) - int(want.B),
+			int(got.A) - int(want.A),
+		} {
+			if diff < -tolerance || diff > tolerance {
+				t.Errorf("pixel at %v = %v, want %v (±%d)", pt, got, want, tolerance)
+				break
+			}
+		}
+	}
+}
+
+func TestEncodeImageRoundTrip(t *testing.T) {
+	p := NewImageProcessor()
+	src := quadrantImage(64, 48)
+
+	tests := []struct {
+		name      string
+		format    string
+		lossless  bool
+		decode    func(io.Reader) (image.Image, error)
+		tolerance int
+	}{
+		{"gif", "gif", false, gif.Decode, 0},
+		{"bmp", "bmp", false, bmp.Decode, 0},
+		{"tiff", "tiff", false, tiff.Decode, 0},
+		{"webp lossy", "webp", false, xwebp.Decode, 24},
+		{"webp lossless", "webp", true, xwebp.Decode, 0},
+		{"png", "png", false, png.Decode, 0},
+		{"jpeg", "jpeg", false, jpeg.Decode, 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			opts := encodeOptions{quality: DefaultQuality, lossless: tt.lossless}
+			if err := p.encodeImage(buf, src, tt.format, opts); err != nil {
+				t.Fatalf("encodeImage: %v", err)
+			}
+
+			img, err := tt.decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			checkQuadrants(t, img, 64, 48, tt.tolerance)
+		})
+	}
+}
+
+func TestEncodeWebPLosslessIsLossless(t *testing.T) {
+	p := NewImageProcessor()
+	src := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 7)
+		if i%4 == 3 {
+			src.Pix[i] = 255
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.encodeImage(buf, src, "webp", encodeOptions{quality: DefaultQuality, lossless: true}); err != nil {
+		t.Fatalf("encodeImage: %v", err)
+	}
+
+	img, err := xwebp.Decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			got := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if want := src.NRGBAAt(x, y); got != want {
+				t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestProcessImageDecodesFormats(t *testing.T) {
+	p := NewImageProcessor()
+	src := quadrantImage(64, 48)
+
+	sources := []struct {
+		format   string
+		lossless bool
+	}{
+		{"webp", true},
+		{"gif", false},
+		{"bmp", false},
+		{"tiff", false},
+	}
+
+	for _, source := range sources {
+		for _, output := range []string{"png", "gif", "bmp", "tiff"} {
+			t.Run(source.format+" to "+output, func(t *testing.T) {
+				encoded := &bytes.Buffer{}
+				opts := encodeOptions{quality: DefaultQuality, lossless: source.lossless}
+				if err := p.encodeImage(encoded, src, source.format, opts); err != nil {
+					t.Fatalf("encodeImage: %v", err)
+				}
+
+				req := &models.AdvancedProcessingRequest{
+					Resize: &models.ResizeRequest{Width: 32, Height: 24, Format: output, Filter: "nearest"},
+				}
+				buf, format, _, err := p.ProcessImage(memFile{bytes.NewReader(encoded.Bytes())}, req)
+				if err != nil {
+					t.Fatalf("ProcessImage: %v", err)
+				}
+				if format != output {
+					t.Errorf("format = %q, want %q", format, output)
+				}
+
+				img, decoded, err := image.Decode(buf)
+				if err != nil {
+					t.Fatalf("decode output: %v", err)
+				}
+				if decoded != output {
+					t.Errorf("output decodes as %q, want %q", decoded, output)
+				}
+				checkQuadrants(t, img, 32, 24, 0)
+			})
+		}
+	}
+}
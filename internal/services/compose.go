@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"mime/multipart"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const (
+	DefaultCellSize   = 300
+	MaxComposeImages  = 100
+	DefaultComposeFit = models.FitCover
+)
+
+// GridSize resolves the grid for count images. Missing rows or columns are
+// derived so every image gets a cell; with neither set the grid is near square.
+func GridSize(count, rows, cols int) (int, int) {
+	switch {
+	case rows <= 0 && cols <= 0:
+		cols = int(math.Ceil(math.Sqrt(float64(count))))
+		rows = (count + cols - 1) / cols
+	case rows <= 0:
+		rows = (count + cols - 1) / cols
+	case cols <= 0:
+		cols = (count + rows - 1) / rows
+	}
+	return rows, cols
+}
+
+// ComposeGrid fits every image into a cell and lays the cells out row by row
+// on one canvas, separated and surrounded by the gutter. Any file larger than
+// maxSize bytes or that cannot be decoded fails the whole grid.
+func (p *ImageProcessor) ComposeGrid(files []multipart.File, maxSize int64, req *models.ComposeRequest) (*bytes.Buffer, string, image.Image, error) {
+	rows, cols := GridSize(len(files), req.Rows, req.Cols)
+	if rows*cols < len(files) {
+		return nil, "", nil, fmt.Errorf("a %dx%d grid cannot hold %d images", rows, cols, len(files))
+	}
+
+	cellWidth, cellHeight := req.CellWidth, req.CellHeight
+	if cellWidth <= 0 {
+		cellWidth = DefaultCellSize
+	}
+	if cellHeight <= 0 {
+		cellHeight = DefaultCellSize
+	}
+	gutter := max(0, req.Gutter)
+
+	width := cols*cellWidth + (cols+1)*gutter
+	height := rows*cellHeight + (rows+1)*gutter
+	if width > MaxCanvasSize || height > MaxCanvasSize {
+		return nil, "", nil, fmt.Errorf("composed image %dx%d exceeds maximum size %d", width, height, MaxCanvasSize)
+	}
+
+	cells := make([]image.Image, len(files))
+	errs := make([]error, len(files))
+	p.runWorkers(len(files), func(i int) {
+		cells[i], errs[i] = p.composeCell(files[i], maxSize, cellWidth, cellHeight, req)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("image %d: %w", i, err)
+		}
+	}
+
+	canvas := imaging.New(width, height, colorOrDefault(req.Background))
+	for i, cell := range cells {
+		row, col := i/cols, i%cols
+		slot := image.Rect(0, 0, cellWidth, cellHeight).Add(image.Pt(
+			gutter+col*(cellWidth+gutter),
+			gutter+row*(cellHeight+gutter),
+		))
+		// Cells smaller than their slot (fit=inside) are centred in it
+		origin := slot.Min.Add(slot.Size().Sub(cell.Bounds().Size()).Div(2))
+		canvas = imaging.Overlay(canvas, cell, origin, 1.0)
+	}
+
+	format := req.Format
+	if format == "" {
+		format = models.FormatJPEG
+	}
+	if !hasAlpha(format) && !canvas.Opaque() {
+		format = models.FormatPNG
+	}
+
+	quality := DefaultQuality
+	if req.Quality > 0 {
+		quality = min(100, req.Quality)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := p.encodeImage(buffer, canvas, format, encodeOptions{quality: quality}); err != nil {
+		return nil, "", nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buffer, format, canvas, nil
+}
+
+// composeCell decodes one image, rotates it upright and fits it to the cell size
+func (p *ImageProcessor) composeCell(file multipart.File, maxSize int64, width, height int, req *models.ComposeRequest) (image.Image, error) {
+	if err := p.ValidateImage(file, maxSize); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	img = applyOrientation(img, readOrientation(file))
+
+	fit := req.Fit
+	if fit == "" {
+		fit = DefaultComposeFit
+	}
+
+	return p.resizeImage(img, &models.ResizeRequest{
+		Width:      width,
+		Height:     height,
+		Fit:        fit,
+		Background: req.Background,
+		Position:   req.Position,
+	}), nil
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	exifOrientationTag = 0x0112
+	maxJPEGSegments    = 64
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// readOrientation returns the EXIF orientation (1-8) of a JPEG or TIFF stream.
+// It returns 1 (upright) when the tag is missing or cannot be parsed.
+func readOrientation(r io.ReaderAt) int {
+	head := make([]byte, 4)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return 1
+	}
+
+	switch {
+	case head[0] == 0xFF && head[1] == 0xD8:
+		if offset, ok := findJPEGExif(r); ok {
+			return readTIFFOrientation(io.NewSectionReader(r, offset, 1<<31))
+		}
+	case bytes.Equal(head, []byte("II*\x00")), bytes.Equal(head, []byte("MM\x00*")):
+		return readTIFFOrientation(r)
+	}
+
+	return 1
+}
+
+// findJPEGExif walks the JPEG marker segments and returns the offset of the
+// TIFF header embedded in the APP1 Exif segment.
+func findJPEGExif(r io.ReaderAt) (int64, bool) {
+	offset := int64(2)
+	segment := make([]byte, 4)
+	header := make([]byte, len(exifHeader))
+
+	for i := 0; i < maxJPEGSegments; i++ {
+		if _, err := r.ReadAt(segment, offset); err != nil || segment[0] != 0xFF {
+			return 0, false
+		}
+
+		marker := segment[1]
+		length := int64(binary.BigEndian.Uint16(segment[2:]))
+		if marker == 0xDA || marker == 0xD9 {
+			return 0, false
+		}
+
+		if marker == 0xE1 {
+			if _, err := r.ReadAt(header, offset+4); err == nil && bytes.Equal(header, exifHeader) {
+				return offset + 4 + int64(len(exifHeader)), true
+			}
+		}
+
+		offset += 2 + length
+	}
+
+	return 0, false
+}
+
+// readTIFFOrientation reads the orientation tag from IFD0 of a TIFF structure
+func readTIFFOrientation(r io.ReaderAt) int {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int64(order.Uint32(header[4:]))
+	count := make([]byte, 2)
+	if _, err := r.ReadAt(count, ifdOffset); err != nil {
+		return 1
+	}
+
+	entry := make([]byte, 12)
+	for i := 0; i < int(order.Uint16(count)); i++ {
+		if _, err := r.ReadAt(entry, ifdOffset+2+int64(i)*12); err != nil {
+			return 1
+		}
+
+		if order.Uint16(entry[:2]) == exifOrientationTag {
+			orientation := int(order.Uint16(entry[8:10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation transforms the image so that it is visually upright
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+const (
+	tagArtist          = 0x013B
+	tagCopyright       = 0x8298
+	tagExifIFD         = 0x8769
+	tagGPSIFD          = 0x8825
+	tagInteropIFD      = 0xA005
+	tagMakerNote       = 0x927C
+	tagPixelXDimension = 0xA002
+	tagPixelYDimension = 0xA003
+)
+
+// TIFF field types that may hold a sub-IFD offset
+const (
+	tiffLong = 4
+	tiffIFD  = 13
+)
+
+// tiffTypeSizes maps TIFF field types to their size in bytes
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1,
+	7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4,
+}
+
+type tiffEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+// exifData holds the IFDs of an EXIF block that survive re-encoding.
+// Offsets are resolved on parse so entries can be filtered and re-serialized.
+type exifData struct {
+	order binary.ByteOrder
+	ifd0  []tiffEntry
+	exif  []tiffEntry
+	gps   []tiffEntry
+}
+
+// parseEXIF parses a TIFF-structured EXIF block (without the "Exif\0\0" prefix).
+// The thumbnail IFD, interoperability IFD and maker notes are dropped because
+// their contents reference offsets or pixels that no longer exist.
+func parseEXIF(data []byte) (*exifData, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("exif block too short")
+	}
+
+	e := &exifData{}
+	switch string(data[:2]) {
+	case "II":
+		e.order = binary.LittleEndian
+	case "MM":
+		e.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid exif byte order")
+	}
+
+	entries, err := e.readIFD(data, e.order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		switch entry.tag {
+		case tagExifIFD, tagGPSIFD:
+			// Pointers that are not a single 32-bit offset are malformed and dropped
+			offset, ok := e.ifdPointer(entry)
+			if !ok {
+				continue
+			}
+			ifd, err := e.readIFD(data, offset)
+			if err != nil {
+				return nil, err
+			}
+			if entry.tag == tagExifIFD {
+				e.exif = ifd
+			} else {
+				e.gps = ifd
+			}
+		default:
+			e.ifd0 = append(e.ifd0, entry)
+		}
+	}
+
+	e.exif = filterEntries(e.exif, func(entry tiffEntry) bool {
+		switch entry.tag {
+		case tagInteropIFD, tagMakerNote, tagPixelXDimension, tagPixelYDimension:
+			return false
+		}
+		return true
+	})
+
+	return e, nil
+}
+
+func (e *exifData) readIFD(data []byte, offset uint32) ([]tiffEntry, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, fmt.Errorf("exif ifd offset out of range")
+	}
+
+	count := uint32(e.order.Uint16(data[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(data)) {
+		return nil, fmt.Errorf("exif ifd truncated")
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		raw := data[offset+2+i*12 : offset+2+(i+1)*12]
+		entry := tiffEntry{
+			tag:   e.order.Uint16(raw[0:]),
+			kind:  e.order.Uint16(raw[2:]),
+			count: e.order.Uint32(raw[4:]),
+		}
+
+		typeSize, ok := tiffTypeSizes[entry.kind]
+		if !ok {
+			continue
+		}
+
+		size := uint64(typeSize) * uint64(entry.count)
+		if size <= 4 {
+			entry.value = append([]byte(nil), raw[8:8+size]...)
+		} else {
+			start := uint64(e.order.Uint32(raw[8:]))
+			if start+size > uint64(len(data)) {
+				continue
+			}
+			entry.value = append([]byte(nil), data[start:start+size]...)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// ifdPointer returns the offset stored in a sub-IFD pointer entry
+func (e *exifData) ifdPointer(entry tiffEntry) (uint32, bool) {
+	if (entry.kind != tiffLong && entry.kind != tiffIFD) || len(entry.value) < 4 {
+		return 0, false
+	}
+	return e.order.Uint32(entry.value), true
+}
+
+// setOrientation overwrites the orientation tag, if present
+func (e *exifData) setOrientation(orientation uint16) {
+	for _, entry := range e.ifd0 {
+		if entry.tag == exifOrientationTag && entry.kind == 3 && len(entry.value) >= 2 {
+			e.order.PutUint16(entry.value, orientation)
+		}
+	}
+}
+
+func (e *exifData) empty() bool {
+	return len(e.ifd0) == 0 && len(e.exif) == 0 && len(e.gps) == 0
+}
+
+// encode serializes the IFDs back into a TIFF-structured EXIF block
+func (e *exifData) encode() []byte {
+	ifd0 := append([]tiffEntry(nil), e.ifd0...)
+	if len(e.exif) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, kind: 4, count: 1, value: make([]byte, 4)})
+	}
+	if len(e.gps) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagGPSIFD, kind: 4, count: 1, value: make([]byte, 4)})
+	}
+	sort.Slice(ifd0, func(i, j int) bool { return ifd0[i].tag < ifd0[j].tag })
+
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset
+	if len(e.exif) > 0 {
+		gpsOffset += ifdSize(e.exif)
+	}
+
+	for _, entry := range ifd0 {
+		switch entry.tag {
+		case tagExifIFD:
+			e.order.PutUint32(entry.value, exifOffset)
+		case tagGPSIFD:
+			e.order.PutUint32(entry.value, gpsOffset)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if e.order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(buf, e.order, uint16(42))
+	binary.Write(buf, e.order, uint32(8))
+
+	e.writeIFD(buf, ifd0)
+	if len(e.exif) > 0 {
+		e.writeIFD(buf, e.exif)
+	}
+	if len(e.gps) > 0 {
+		e.writeIFD(buf, e.gps)
+	}
+
+	return buf.Bytes()
+}
+
+// writeIFD writes an IFD at the current end of buf, followed by its out-of-line values
+func (e *exifData) writeIFD(buf *bytes.Buffer, entries []tiffEntry) {
+	start := uint32(buf.Len())
+	dataOffset := start + 2 + uint32(len(entries))*12 + 4
+
+	var data []byte
+	binary.Write(buf, e.order, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, e.order, entry.tag)
+		binary.Write(buf, e.order, entry.kind)
+		binary.Write(buf, e.order, entry.count)
+
+		if len(entry.value) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, entry.value)
+			buf.Write(inline)
+			continue
+		}
+
+		binary.Write(buf, e.order, dataOffset+uint32(len(data)))
+		data = append(data, entry.value...)
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	binary.Write(buf, e.order, uint32(0))
+	buf.Write(data)
+}
+
+// ifdSize returns the encoded size of an IFD including its out-of-line values
+func ifdSize(entries []tiffEntry) uint32 {
+	size := 2 + uint32(len(entries))*12 + 4
+	for _, entry := range entries {
+		if n := uint32(len(entry.value)); n > 4 {
+			size += n + n%2
+		}
+	}
+	return size
+}
+
+func filterEntries(entries []tiffEntry, keep func(tiffEntry) bool) []tiffEntry {
+	var kept []tiffEntry
+	for _, entry := range entries {
+		if keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
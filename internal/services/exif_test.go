@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// rawIFD0 builds a TIFF block holding a single IFD0 with inline entries
+func rawIFD0(order binary.ByteOrder, entries []tiffEntry) []byte {
+	buf := &bytes.Buffer{}
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(buf, order, uint16(42))
+	binary.Write(buf, order, uint32(8))
+	binary.Write(buf, order, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, order, entry.tag)
+		binary.Write(buf, order, entry.kind)
+		binary.Write(buf, order, entry.count)
+		inline := make([]byte, 4)
+		copy(inline, entry.value)
+		buf.Write(inline)
+	}
+	binary.Write(buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+func TestParseEXIFRejectsShortPointers(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		orientation := make([]byte, 2)
+		order.PutUint16(orientation, 6)
+		pointer := make([]byte, 2)
+		order.PutUint16(pointer, 8)
+
+		data := rawIFD0(order, []tiffEntry{
+			{tag: exifOrientationTag, kind: 3, count: 1, value: orientation},
+			{tag: tagExifIFD, kind: 3, count: 1, value: pointer},
+			{tag: tagGPSIFD, kind: 1, count: 1, value: []byte{8}},
+		})
+
+		e, err := parseEXIF(data)
+		if err != nil {
+			t.Fatalf("%v: parseEXIF: %v", order, err)
+		}
+		if len(e.exif) != 0 || len(e.gps) != 0 {
+			t.Errorf("%v: malformed pointers were followed: exif=%v gps=%v", order, e.exif, e.gps)
+		}
+		if len(e.ifd0) != 1 || e.ifd0[0].tag != exifOrientationTag {
+			t.Errorf("%v: ifd0 = %v, want only the orientation tag", order, e.ifd0)
+		}
+	}
+}
+
+func TestParseEXIFRoundTrip(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		copyright := []byte("(c) ACME\x00")
+		latitudeRef := []byte("N\x00")
+		exposure := make([]byte, 8)
+		order.PutUint32(exposure, 1)
+		order.PutUint32(exposure[4:], 250)
+
+		original := &exifData{
+			order: order,
+			ifd0:  []tiffEntry{{tag: tagCopyright, kind: 2, count: uint32(len(copyright)), value: copyright}},
+			exif:  []tiffEntry{{tag: 0x829A, kind: 5, count: 1, value: exposure}},
+			gps:   []tiffEntry{{tag: 0x0001, kind: 2, count: 2, value: latitudeRef}},
+		}
+
+		e, err := parseEXIF(original.encode())
+		if err != nil {
+			t.Fatalf("%v: parseEXIF: %v", order, err)
+		}
+
+		check := func(name string, got, want []tiffEntry) {
+			if len(got) != len(want) {
+				t.Fatalf("%v: %s has %d entries, want %d", order, name, len(got), len(want))
+			}
+			for i := range want {
+				if got[i].tag != want[i].tag || got[i].kind != want[i].kind ||
+					got[i].count != want[i].count || !bytes.Equal(got[i].value, want[i].value) {
+					t.Errorf("%v: %s[%d] = %+v, want %+v", order, name, i, got[i], want[i])
+				}
+			}
+		}
+		check("ifd0", e.ifd0, original.ifd0)
+		check("exif", e.exif, original.exif)
+		check("gps", e.gps, original.gps)
+	}
+}
+
+func TestParseEXIFTruncated(t *testing.T) {
+	data := rawIFD0(binary.LittleEndian, []tiffEntry{
+		{tag: tagExifIFD, kind: tiffLong, count: 1, value: []byte{0xFF, 0xFF, 0, 0}},
+	})
+
+	if _, err := parseEXIF(data); err == nil {
+		t.Error("expected an error for an out-of-range exif ifd pointer")
+	}
+	if _, err := parseEXIF(data[:6]); err == nil {
+		t.Error("expected an error for a short block")
+	}
+}
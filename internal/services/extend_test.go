@@ -0,0 +1,56 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestExtendImageNeverCropsOrPassesMaxCanvasSize(t *testing.T) {
+	p := NewImageProcessor()
+	red := color.NRGBA{255, 0, 0, 255}
+
+	tests := []struct {
+		name    string
+		width   int
+		req     models.ExtendRequest
+		canvas  int
+		originX int
+	}{
+		{"target smaller than image", 200, models.ExtendRequest{Width: 100}, 200, 0},
+		{"target past limit", 9000, models.ExtendRequest{Width: 20000}, MaxCanvasSize, 500},
+		{"image past limit", MaxCanvasSize + 10, models.ExtendRequest{Width: MaxCanvasSize + 500}, MaxCanvasSize + 10, 0},
+		{"padding within limit", 100, models.ExtendRequest{Left: models.Length{Value: 10}, Right: models.Length{Value: 30}}, 140, 10},
+		{"padding past limit", 9000, models.ExtendRequest{Left: models.Length{Value: 1000}, Right: models.Length{Value: 3000}}, MaxCanvasSize, 250},
+		{"padding on oversized image", MaxCanvasSize + 10, models.ExtendRequest{Left: models.Length{Value: 50}, Right: models.Length{Value: 50}}, MaxCanvasSize + 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, 2))
+			for x := 0; x < tt.width; x++ {
+				img.SetNRGBA(x, 0, red)
+				img.SetNRGBA(x, 1, red)
+			}
+
+			out := p.extendImage(img, &tt.req)
+			if got := out.Bounds().Dx(); got != tt.canvas {
+				t.Fatalf("canvas width = %d, want %d", got, tt.canvas)
+			}
+
+			// The whole image is on the canvas at the expected offset
+			for _, x := range []int{tt.originX, tt.originX + tt.width - 1} {
+				if got := color.NRGBAModel.Convert(out.At(x, 0)); got != red {
+					t.Errorf("pixel at x=%d = %v, want the image", x, got)
+				}
+			}
+			for _, x := range []int{tt.originX - 1, tt.originX + tt.width} {
+				if x >= 0 && x < tt.canvas && color.NRGBAModel.Convert(out.At(x, 0)) == red {
+					t.Errorf("padding pixel at x=%d belongs to the image", x)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+// DefaultFilter is the resampling filter used when a request does not name one
+const DefaultFilter = "lanczos"
+
+// gravities maps a gravity to the relative position of the kept window
+var gravities = map[string]struct{ x, y float64 }{
+	models.GravityCenter:    {0.5, 0.5},
+	models.GravityNorth:     {0.5, 0},
+	models.GravitySouth:     {0.5, 1},
+	models.GravityEast:      {1, 0.5},
+	models.GravityWest:      {0, 0.5},
+	models.GravityNorthEast: {1, 0},
+	models.GravityNorthWest: {0, 0},
+	models.GravitySouthEast: {1, 1},
+	models.GravitySouthWest: {0, 1},
+}
+
+var resampleFilters = map[string]imaging.ResampleFilter{
+	"nearest":     imaging.NearestNeighbor,
+	"box":         imaging.Box,
+	"linear":      imaging.Linear,
+	"hermite":     imaging.Hermite,
+	"mitchell":    imaging.MitchellNetravali,
+	"catmull-rom": imaging.CatmullRom,
+	"bspline":     imaging.BSpline,
+	"gaussian":    imaging.Gaussian,
+	"bartlett":    imaging.Bartlett,
+	"lanczos":     imaging.Lanczos,
+	"hann":        imaging.Hann,
+	"hamming":     imaging.Hamming,
+	"blackman":    imaging.Blackman,
+	"welch":       imaging.Welch,
+	"cosine":      imaging.Cosine,
+}
+
+// DefaultBackground is used to fill empty canvas areas when no colour is given
+var DefaultBackground = color.NRGBA{255, 255, 255, 255}
+
+// ParseColor parses "#rgb", "#rrggbb", "#rrggbbaa" or "transparent".
+// An empty string yields DefaultBackground.
+func ParseColor(value string) (color.NRGBA, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultBackground, nil
+	}
+	if value == "transparent" {
+		return color.NRGBA{}, nil
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: expected #rgb, #rrggbb or #rrggbbaa", value)
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: %w", value, err)
+	}
+
+	return color.NRGBA{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+}
+
+// colorOrDefault parses a colour that was already validated by the handler
+func colorOrDefault(value string) color.NRGBA {
+	c, err := ParseColor(value)
+	if err != nil {
+		return DefaultBackground
+	}
+	return c
+}
+
+// LookupFilter returns the resampling filter registered under name.
+// An empty name yields DefaultFilter.
+func LookupFilter(name string) (imaging.ResampleFilter, error) {
+	if name == "" {
+		name = DefaultFilter
+	}
+
+	filter, ok := resampleFilters[strings.ToLower(name)]
+	if !ok {
+		return imaging.ResampleFilter{}, fmt.Errorf("unknown resampling filter %q", name)
+	}
+
+	return filter, nil
+}
+
+// filterOrDefault looks up a filter that was already validated by the handler
+func filterOrDefault(name string) imaging.ResampleFilter {
+	filter, err := LookupFilter(name)
+	if err != nil {
+		return imaging.Lanczos
+	}
+	return filter
+}
+
+// shiftHue rotates the hue of a colour by the given number of degrees
+func shiftHue(c color.NRGBA, degrees float64) color.NRGBA {
+	h, s, l := rgbToHSL(c)
+	h = math.Mod(h+degrees/360+1, 1)
+	r, g, b := hslToRGB(h, s, l)
+	return color.NRGBA{r, g, b, c.A}
+}
+
+// rgbToHSL converts a colour to hue, saturation and lightness in the range [0, 1]
+func rgbToHSL(c color.NRGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC, minC := max(r, g, b), min(r, g, b)
+	l := (maxC + minC) / 2
+
+	if maxC == minC {
+		return 0, 0, l
+	}
+
+	d := maxC - minC
+	s := d / (maxC + minC)
+	if l > 0.5 {
+		s = d / (2 - maxC - minC)
+	}
+
+	var h float64
+	switch maxC {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+
+	return h / 6, s, l
+}
+
+// hslToRGB converts hue, saturation and lightness in the range [0, 1] to RGB
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	if s == 0 {
+		v := uint8(math.Round(l * 255))
+		return v, v, v
+	}
+
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+
+	toChannel := func(t float64) uint8 {
+		t = math.Mod(t+1, 1)
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 0.5:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return uint8(math.Round(v * 255))
+	}
+
+	return toChannel(h + 1.0/3), toChannel(h), toChannel(h - 1.0/3)
+}
+
+// ParseAspectRatio parses a ratio such as "16:9", "4/3" or "1.5"
+func ParseAspectRatio(value string) (float64, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ':' || r == '/' })
+
+	var ratio float64
+	switch len(parts) {
+	case 1:
+		v, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid aspect ratio %q", value)
+		}
+		ratio = v
+	case 2:
+		w, errW := strconv.ParseFloat(parts[0], 64)
+		h, errH := strconv.ParseFloat(parts[1], 64)
+		if errW != nil || errH != nil || h <= 0 {
+			return 0, fmt.Errorf("invalid aspect ratio %q", value)
+		}
+		ratio = w / h
+	default:
+		return 0, fmt.Errorf("invalid aspect ratio %q: expected W:H", value)
+	}
+
+	if ratio <= 0 || math.IsInf(ratio, 0) || math.IsNaN(ratio) {
+		return 0, fmt.Errorf("invalid aspect ratio %q: must be positive", value)
+	}
+
+	return ratio, nil
+}
+
+// ValidateGravity reports whether name is a known gravity. An empty name means center.
+func ValidateGravity(name string) error {
+	if _, ok := gravities[name]; !ok && name != "" {
+		return fmt.Errorf("unknown gravity %q", name)
+	}
+	return nil
+}
+
+// gravityRect places a width x height window inside bounds according to the gravity
+func gravityRect(bounds image.Rectangle, width, height int, name string) image.Rectangle {
+	g, ok := gravities[name]
+	if !ok {
+		g = gravities[models.GravityCenter]
+	}
+
+	x := int(math.Round(float64(bounds.Dx()-width) * g.x))
+	y := int(math.Round(float64(bounds.Dy()-height) * g.y))
+
+	return image.Rect(x, y, x+width, y+height).Add(bounds.Min)
+}
@@ -5,31 +5,76 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/color/palette"
 	"image/draw"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"math"
 	"mime/multipart"
 	"sync"
 
+	"github.com/chai2010/webp"
 	"github.com/disintegration/imaging"
 	"github.com/phambaophuc/image-resize/internal/models"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 )
 
+// Blurred extend fill: the image is shrunk by extendBlurDownscale before blurring
 const (
-	DefaultQuality   = 85
-	DefaultWorkers   = 5
-	WatermarkPadding = 10
-	MaxFileSize      = 10 << 20 // 10MB
+	extendBlurDownscale = 10
+	extendBlurSigma     = 3.0
 )
 
-type ImageProcessor struct{}
+// DefaultSharpen is the mild unsharp mask applied after downscaling
+var DefaultSharpen = models.SharpenRequest{Amount: 0.6, Radius: 0.6, Threshold: 2}
 
-func NewImageProcessor() *ImageProcessor {
-	return &ImageProcessor{}
+const (
+	DefaultQuality        = 85
+	DefaultWorkers        = 5
+	WatermarkPadding      = 10
+	DefaultWatermarkScale = 0.2      // logo width relative to the base image width
+	DefaultOpacity        = 1.0      // watermark opacity when the request leaves it out
+	MaxFileSize           = 10 << 20 // 10MB
+	MaxCanvasSize         = 10000    // largest width or height an extended canvas may have
+
+	gifAlphaThreshold = 0x80 // pixels less opaque than this are transparent in GIF output
+)
+
+type ImageProcessor struct {
+	fontDir string
+	fonts   map[string]*opentype.Font
+	fontsMu sync.RWMutex
+}
+
+type ProcessorOptions struct {
+	FontDir string
+}
+
+var DefaultProcessorOptions = ProcessorOptions{
+	FontDir: "./fonts",
+}
+
+type encodeOptions struct {
+	quality  int
+	lossless bool
+	metadata *imageMetadata
+}
+
+func NewImageProcessor(opts ...ProcessorOptions) *ImageProcessor {
+	options := DefaultProcessorOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	return &ImageProcessor{
+		fontDir: options.FontDir,
+		fonts:   make(map[string]*opentype.Font),
+	}
 }
 
 // ProcessImage handles single image processing with all operations
@@ -46,11 +91,32 @@ func (p *ImageProcessor) ProcessImage(
 		return nil, "", nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
+	if !request.DisableAutoOrient {
+		img = applyOrientation(img, readOrientation(file))
+	}
+
 	processedImg := p.applyTransformations(img, request)
 	outputFormat := p.getOutputFormat(format, request)
 
+	// Keep transparency from masks, transparent fills and sources that carry
+	// alpha: switch formats that cannot store it to PNG, or flatten onto the
+	// requested background. Callers must use the returned format.
+	if !hasAlpha(outputFormat) && !isOpaque(processedImg) {
+		if request.Flatten != "" {
+			processedImg = flattenImage(processedImg, request.Flatten)
+		} else {
+			outputFormat = "png"
+		}
+	}
+
+	opts := encodeOptions{
+		quality:  p.getQuality(request),
+		lossless: p.isLossless(request),
+		metadata: p.getMetadata(file, request),
+	}
+
 	buffer := &bytes.Buffer{}
-	if err := p.encodeImage(buffer, processedImg, outputFormat, p.getQuality(request)); err != nil {
+	if err := p.encodeImage(buffer, processedImg, outputFormat, opts); err != nil {
 		return nil, "", nil, fmt.Errorf("failed to encode image: %w", err)
 	}
 
@@ -60,11 +126,19 @@ func (p *ImageProcessor) ProcessImage(
 // BatchResize processes multiple images concurrently
 func (p *ImageProcessor) BatchResize(files []multipart.File, req *models.AdvancedProcessingRequest) []models.BatchImage {
 	results := make([]models.BatchImage, len(files))
-	jobs := make(chan int, len(files))
+	p.runWorkers(len(files), func(i int) {
+		p.processImageJob(i, files, req, results)
+	})
+	return results
+}
+
+// runWorkers calls job for every index in [0, count) on a pool of DefaultWorkers goroutines
+func (p *ImageProcessor) runWorkers(count int, job func(i int)) {
+	jobs := make(chan int, count)
 
 	numWorkers := DefaultWorkers
-	if len(files) < numWorkers {
-		numWorkers = len(files)
+	if count < numWorkers {
+		numWorkers = count
 	}
 
 	var wg sync.WaitGroup
@@ -74,18 +148,16 @@ func (p *ImageProcessor) BatchResize(files []multipart.File, req *models.Advance
 		go func() {
 			defer wg.Done()
 			for i := range jobs {
-				p.processImageJob(i, files, req, results)
+				job(i)
 			}
 		}()
 	}
 
-	for i := range files {
+	for i := 0; i < count; i++ {
 		jobs <- i
 	}
 	close(jobs)
 	wg.Wait()
-
-	return results
 }
 
 // validateImage validates file size and format
@@ -102,50 +174,288 @@ func (p *ImageProcessor) ValidateImage(file multipart.File, maxSize int64) error
 	return nil
 }
 
-// applyTransformations applies all image transformations in sequence
+// applyTransformations runs the request's pipeline steps in order
 func (p *ImageProcessor) applyTransformations(img image.Image, request *models.AdvancedProcessingRequest) image.Image {
 	result := img
-
-	if request.Crop != nil {
-		result = p.cropImage(result, request.Crop)
-	}
-
-	if request.Resize != nil {
-		result = p.resizeImage(result, request.Resize)
+	for _, op := range request.Pipeline() {
+		result = p.applyOperation(result, op)
 	}
+	return result
+}
 
-	if request.Watermark != nil {
-		result = p.addWatermark(result, request.Watermark)
+// applyOperation applies a single pipeline step
+func (p *ImageProcessor) applyOperation(img image.Image, op models.Operation) image.Image {
+	switch {
+	case op.Crop != nil:
+		return p.cropImage(img, op.Crop)
+	case op.Rotate != nil:
+		return p.rotateImage(img, op.Rotate)
+	case op.Flip != "":
+		return p.flipImage(img, op.Flip)
+	case op.Resize != nil:
+		resized := p.resizeImage(img, op.Resize)
+		if op.Resize.SharpenOnDownscale && resized.Bounds().Dx() < img.Bounds().Dx() {
+			resized = p.sharpenImage(resized, &DefaultSharpen)
+		}
+		return resized
+	case op.Extend != nil:
+		return p.extendImage(img, op.Extend)
+	case op.Adjust != nil:
+		return p.adjustImage(img, op.Adjust)
+	case op.Blur != 0:
+		return imaging.Blur(img, op.Blur)
+	case op.Sharpen != nil:
+		return p.sharpenImage(img, op.Sharpen)
+	case op.Watermark != nil:
+		return p.addWatermark(img, op.Watermark)
+	case op.Border != nil:
+		return p.borderImage(img, op.Border)
+	case op.Mask != nil:
+		return p.maskImage(img, op.Mask)
+	default:
+		return img
 	}
-
-	return result
 }
 
 // cropImage crops the image based on the crop request
 func (p *ImageProcessor) cropImage(img image.Image, req *models.CropRequest) image.Image {
 	bounds := img.Bounds()
 
+	if req.Mode == models.CropModeSmart {
+		width, height := p.cropWindowSize(bounds, req)
+		return imaging.Crop(img, smartCrop(img, width, height))
+	}
+
+	x, y := req.X.Resolve(bounds.Dx()), req.Y.Resolve(bounds.Dy())
+	width, height := req.Width.Resolve(bounds.Dx()), req.Height.Resolve(bounds.Dy())
+	if req.AspectRatio != "" || req.Gravity != "" {
+		width, height = p.cropWindowSize(bounds, req)
+		window := gravityRect(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), width, height, req.Gravity)
+		x, y = window.Min.X, window.Min.Y
+	}
+
 	// Validate crop boundaries
-	x := max(0, min(req.X, bounds.Dx()))
-	y := max(0, min(req.Y, bounds.Dy()))
-	width := min(req.Width, bounds.Dx()-x)
-	height := min(req.Height, bounds.Dy()-y)
+	x = max(0, min(x, bounds.Dx()))
+	y = max(0, min(y, bounds.Dy()))
+	width = min(width, bounds.Dx()-x)
+	height = min(height, bounds.Dy()-y)
 
 	cropBounds := image.Rect(x, y, x+width, y+height)
 	return imaging.Crop(img, cropBounds)
 }
 
-// resizeImage resizes the image using Lanczos resampling
+// rotateImage rotates the image clockwise, filling exposed corners with the background colour
+func (p *ImageProcessor) rotateImage(img image.Image, req *models.RotateRequest) image.Image {
+	return imaging.Rotate(img, -req.Angle, colorOrDefault(req.Background))
+}
+
+// flipImage mirrors the image horizontally, vertically or both
+func (p *ImageProcessor) flipImage(img image.Image, direction string) image.Image {
+	switch direction {
+	case models.FlipHorizontal:
+		return imaging.FlipH(img)
+	case models.FlipVertical:
+		return imaging.FlipV(img)
+	case models.FlipBoth:
+		return imaging.Rotate180(img)
+	default:
+		return img
+	}
+}
+
+// cropWindowSize returns the largest window matching the requested aspect ratio,
+// or the requested size scaled down to fit inside the image
+func (p *ImageProcessor) cropWindowSize(bounds image.Rectangle, req *models.CropRequest) (int, int) {
+	if ratio, err := ParseAspectRatio(req.AspectRatio); req.AspectRatio != "" && err == nil {
+		if float64(bounds.Dx())/float64(bounds.Dy()) > ratio {
+			return max(1, int(math.Round(float64(bounds.Dy())*ratio))), bounds.Dy()
+		}
+		return bounds.Dx(), max(1, int(math.Round(float64(bounds.Dx())/ratio)))
+	}
+
+	width, height := max(1, req.Width.Resolve(bounds.Dx())), max(1, req.Height.Resolve(bounds.Dy()))
+	if scale := min(float64(bounds.Dx())/float64(width), float64(bounds.Dy())/float64(height)); scale < 1 {
+		width = max(1, int(math.Round(float64(width)*scale)))
+		height = max(1, int(math.Round(float64(height)*scale)))
+	}
+
+	return width, height
+}
+
+// resizeImage resizes the image with the requested resampling filter according to the fit mode
 func (p *ImageProcessor) resizeImage(img image.Image, req *models.ResizeRequest) image.Image {
-	width := max(1, req.Width)
-	height := max(1, req.Height)
+	bounds := img.Bounds()
+	width, height := p.resolveDimensions(bounds, req.Width, req.Height)
+	filter := filterOrDefault(req.Filter)
+
+	scaleX := float64(width) / float64(bounds.Dx())
+	scaleY := float64(height) / float64(bounds.Dy())
+
+	switch req.Fit {
+	case models.FitCover:
+		scale := max(scaleX, scaleY)
+		enlarge := scale > 1
+		if req.Position == "" && !(req.WithoutEnlargement && enlarge) {
+			return imaging.Fill(img, width, height, imaging.Center, filter)
+		}
+		// The window is the largest region of the target aspect ratio that
+		// fits the source; without enlargement it is returned unscaled
+		windowW := max(1, min(bounds.Dx(), int(math.Round(float64(width)/scale))))
+		windowH := max(1, min(bounds.Dy(), int(math.Round(float64(height)/scale))))
+		window := gravityRect(bounds, windowW, windowH, req.Position)
+		if req.Position == models.PositionSmart {
+			window = smartCrop(img, windowW, windowH)
+		}
+		cropped := imaging.Crop(img, window)
+		if req.WithoutEnlargement && enlarge {
+			return cropped
+		}
+		return imaging.Resize(cropped, width, height, filter)
+	case models.FitContain:
+		scaled := p.scaleImage(img, min(scaleX, scaleY), req.WithoutEnlargement, filter)
+		canvas := imaging.New(width, height, colorOrDefault(req.Background))
+		return imaging.PasteCenter(canvas, scaled)
+	case models.FitInside:
+		return p.scaleImage(img, min(scaleX, scaleY), req.WithoutEnlargement, filter)
+	case models.FitOutside:
+		return p.scaleImage(img, max(scaleX, scaleY), req.WithoutEnlargement, filter)
+	default:
+		if req.WithoutEnlargement {
+			width = min(width, bounds.Dx())
+			height = min(height, bounds.Dy())
+		}
+		return imaging.Resize(img, width, height, filter)
+	}
+}
+
+// resolveDimensions derives a missing width or height from the source aspect ratio
+func (p *ImageProcessor) resolveDimensions(bounds image.Rectangle, width, height int) (int, int) {
+	switch {
+	case width <= 0 && height <= 0:
+		return bounds.Dx(), bounds.Dy()
+	case width <= 0:
+		width = int(math.Round(float64(height) * float64(bounds.Dx()) / float64(bounds.Dy())))
+	case height <= 0:
+		height = int(math.Round(float64(width) * float64(bounds.Dy()) / float64(bounds.Dx())))
+	}
+
+	return max(1, width), max(1, height)
+}
+
+// scaleImage scales both dimensions by the same factor, preserving aspect ratio
+func (p *ImageProcessor) scaleImage(img image.Image, scale float64, withoutEnlargement bool, filter imaging.ResampleFilter) image.Image {
+	if withoutEnlargement && scale > 1 {
+		scale = 1
+	}
+
+	bounds := img.Bounds()
+	width := max(1, int(math.Round(float64(bounds.Dx())*scale)))
+	height := max(1, int(math.Round(float64(bounds.Dy())*scale)))
+
+	return imaging.Resize(img, width, height, filter)
+}
+
+// extendImage pads the image by per-side lengths, or places it on a target
+// canvas by gravity, filling the new area with a colour or a blurred copy
+func (p *ImageProcessor) extendImage(img image.Image, req *models.ExtendRequest) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var canvasWidth, canvasHeight int
+	var origin image.Point
+	if req.Width > 0 || req.Height > 0 {
+		// The canvas never crops: a dimension smaller than the image keeps the
+		// image size, and one past MaxCanvasSize stops there or at the image size
+		canvasWidth = max(width, min(req.Width, MaxCanvasSize))
+		canvasHeight = max(height, min(req.Height, MaxCanvasSize))
+		origin = gravityRect(image.Rect(0, 0, canvasWidth, canvasHeight), width, height, req.Gravity).Min
+	} else {
+		// Padding that would pass MaxCanvasSize is scaled down on both sides
+		// alike, so the image keeps its relative position
+		top, bottom := fitPadding(max(0, req.Top.Resolve(height)), max(0, req.Bottom.Resolve(height)), MaxCanvasSize-height)
+		left, right := fitPadding(max(0, req.Left.Resolve(width)), max(0, req.Right.Resolve(width)), MaxCanvasSize-width)
+		canvasWidth, canvasHeight = width+left+right, height+top+bottom
+		origin = image.Pt(left, top)
+	}
+
+	var canvas *image.NRGBA
+	if req.Fill == models.ExtendFillBlur {
+		// Blur a small cover-scaled copy and enlarge it; cheaper than a wide blur at full size
+		small := imaging.Fill(img, max(1, canvasWidth/extendBlurDownscale), max(1, canvasHeight/extendBlurDownscale),
+			imaging.Center, imaging.Linear)
+		canvas = imaging.Resize(imaging.Blur(small, extendBlurSigma), canvasWidth, canvasHeight, imaging.Linear)
+	} else {
+		canvas = imaging.New(canvasWidth, canvasHeight, colorOrDefault(req.Background))
+	}
+
+	return imaging.Overlay(canvas, img, origin, 1.0)
+}
+
+// fitPadding scales a pair of paddings proportionally so that together they
+// are no larger than room
+func fitPadding(a, b, room int) (int, int) {
+	room = max(0, room)
+	if a+b <= room {
+		return a, b
+	}
+	a = a * room / (a + b)
+	return a, room - a
+}
 
-	return imaging.Resize(img, width, height, imaging.Lanczos)
+// adjustImage applies colour corrections; zero values leave the image unchanged
+func (p *ImageProcessor) adjustImage(img image.Image, req *models.AdjustRequest) image.Image {
+	result := img
+
+	if req.Brightness != 0 {
+		result = imaging.AdjustBrightness(result, req.Brightness)
+	}
+
+	if req.Contrast != 0 {
+		result = imaging.AdjustContrast(result, req.Contrast)
+	}
+
+	if req.Gamma != 0 && req.Gamma != 1 {
+		result = imaging.AdjustGamma(result, req.Gamma)
+	}
+
+	if req.Saturation != 0 {
+		result = imaging.AdjustSaturation(result, req.Saturation)
+	}
+
+	if req.Hue != 0 {
+		result = imaging.AdjustFunc(result, func(c color.NRGBA) color.NRGBA {
+			return shiftHue(c, req.Hue)
+		})
+	}
+
+	return result
+}
+
+// sharpenImage applies an unsharp mask: pixels are pushed away from their
+// gaussian-blurred value by amount, ignoring differences below threshold
+func (p *ImageProcessor) sharpenImage(img image.Image, req *models.SharpenRequest) image.Image {
+	src := imaging.Clone(img)
+	blurred := imaging.Blur(src, req.Radius)
+	dst := image.NewNRGBA(src.Bounds())
+
+	for i := 0; i < len(src.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			diff := float64(src.Pix[i+c]) - float64(blurred.Pix[i+c])
+			value := float64(src.Pix[i+c])
+			if math.Abs(diff) >= float64(req.Threshold) {
+				value += req.Amount * diff
+			}
+			dst.Pix[i+c] = uint8(min(255, max(0, math.Round(value))))
+		}
+		dst.Pix[i+3] = src.Pix[i+3]
+	}
+
+	return dst
 }
 
-// addWatermark adds text watermark to the image
+// addWatermark adds image and/or text watermark to the image
 func (p *ImageProcessor) addWatermark(img image.Image, req *models.WatermarkRequest) image.Image {
-	if req.Text == "" {
+	if req.Text == "" && req.Image == nil {
 		return img
 	}
 
@@ -153,62 +463,218 @@ func (p *ImageProcessor) addWatermark(img image.Image, req *models.WatermarkRequ
 	watermarked := image.NewRGBA(bounds)
 	draw.Draw(watermarked, bounds, img, bounds.Min, draw.Src)
 
-	p.drawTextWatermark(watermarked, req)
+	if req.Mode == models.WatermarkModeTile {
+		p.drawTiledWatermark(watermarked, req)
+		return watermarked
+	}
+
+	if req.Image != nil {
+		p.drawImageWatermark(watermarked, req)
+	}
+
+	if req.Text != "" {
+		p.drawTextWatermark(watermarked, req)
+	}
 	return watermarked
 }
 
-// drawTextWatermark draws text watermark at specified position
-func (p *ImageProcessor) drawTextWatermark(img *image.RGBA, req *models.WatermarkRequest) {
-	bounds := img.Bounds()
+// drawImageWatermark scales the logo relative to the base width and blends it
+// with the requested opacity. draw.Over on premultiplied RGBA keeps edges of
+// semi-transparent logos correct.
+func (p *ImageProcessor) drawImageWatermark(img *image.RGBA, req *models.WatermarkRequest) {
+	logo := p.scaleWatermark(img.Bounds(), req)
+	origin := p.watermarkOrigin(img.Bounds(), logo.Bounds().Size(), req)
+	compositeLayer(img, logo, origin, watermarkOpacity(req))
+}
+
+// scaleWatermark resizes the logo to scale times the base width, never taller than the base
+func (p *ImageProcessor) scaleWatermark(bounds image.Rectangle, req *models.WatermarkRequest) *image.NRGBA {
+	scale := req.Scale
+	if scale <= 0 {
+		scale = DefaultWatermarkScale
+	}
+
+	width := max(1, int(math.Round(float64(bounds.Dx())*scale)))
+	logo := imaging.Resize(req.Image, width, 0, imaging.Lanczos)
+	if logo.Bounds().Dy() > bounds.Dy() {
+		logo = imaging.Resize(req.Image, 0, bounds.Dy(), imaging.Lanczos)
+	}
+	return logo
+}
+
+// watermarkMargin resolves the margin; percentages are relative to the shorter side
+func (p *ImageProcessor) watermarkMargin(bounds image.Rectangle, req *models.WatermarkRequest) int {
+	if req.Margin == nil {
+		return WatermarkPadding
+	}
+	return max(0, req.Margin.Resolve(min(bounds.Dx(), bounds.Dy())))
+}
+
+// watermarkOpacity returns the requested opacity, or DefaultOpacity when it is absent
+func watermarkOpacity(req *models.WatermarkRequest) float64 {
+	if req.Opacity == nil {
+		return DefaultOpacity
+	}
+	return *req.Opacity
+}
 
-	// Calculate position using map for cleaner code
-	positions := map[string]struct{ x, y int }{
-		"top-left":     {WatermarkPadding, 30},
-		"top-right":    {bounds.Dx() - 100, 30},
-		"bottom-left":  {WatermarkPadding, bounds.Dy() - WatermarkPadding},
-		"bottom-right": {bounds.Dx() - 100, bounds.Dy() - WatermarkPadding},
-		"center":       {bounds.Dx()/2 - 50, bounds.Dy() / 2},
+// watermarkOrigin returns the top-left corner for a watermark of the given size,
+// anchored by its measured box and kept inside the image bounds
+func (p *ImageProcessor) watermarkOrigin(bounds image.Rectangle, size image.Point, req *models.WatermarkRequest) image.Point {
+	margin := p.watermarkMargin(bounds, req)
+
+	left := bounds.Min.X + margin
+	right := bounds.Max.X - margin - size.X
+	top := bounds.Min.Y + margin
+	bottom := bounds.Max.Y - margin - size.Y
+	centerX := bounds.Min.X + (bounds.Dx()-size.X)/2
+	centerY := bounds.Min.Y + (bounds.Dy()-size.Y)/2
+
+	positions := map[string]image.Point{
+		models.PositionTopLeft:      {left, top},
+		models.PositionTopCenter:    {centerX, top},
+		models.PositionTopRight:     {right, top},
+		models.PositionLeftCenter:   {left, centerY},
+		models.PositionCenter:       {centerX, centerY},
+		models.PositionRightCenter:  {right, centerY},
+		models.PositionBottomLeft:   {left, bottom},
+		models.PositionBottomCenter: {centerX, bottom},
+		models.PositionBottomRight:  {right, bottom},
 	}
 
-	pos, exists := positions[req.Position]
+	origin, exists := positions[req.Position]
 	if !exists {
-		pos = positions["bottom-right"]
+		origin = positions[models.PositionBottomRight]
+	}
+	origin = origin.Add(image.Pt(req.OffsetX.Resolve(bounds.Dx()), req.OffsetY.Resolve(bounds.Dy())))
+
+	origin.X = max(bounds.Min.X, min(origin.X, bounds.Max.X-size.X))
+	origin.Y = max(bounds.Min.Y, min(origin.Y, bounds.Max.Y-size.Y))
+	return origin
+}
+
+// DecodeWatermark decodes a watermark image from any supported format
+func (p *ImageProcessor) DecodeWatermark(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(io.LimitReader(r, MaxFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid watermark image: %w", err)
 	}
+	return img, nil
+}
 
-	// Draw text
-	opacity := min(1.0, max(0.0, req.Opacity))
-	textColor := image.NewUniform(color.RGBA{200, 200, 200, uint8(255 * opacity)})
+// drawTextWatermark renders the text with the requested font and places the
+// measured text box at the specified position
+func (p *ImageProcessor) drawTextWatermark(img *image.RGBA, req *models.WatermarkRequest) {
+	layer, origin, err := p.fitTextWatermark(img.Bounds(), req)
+	if err != nil {
+		return
+	}
+	compositeLayer(img, layer, origin, watermarkOpacity(req))
+}
 
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  textColor,
-		Face: basicfont.Face7x13,
-		Dot:  fixed.Point26_6{X: fixed.I(pos.x), Y: fixed.I(pos.y)},
+// fitTextWatermark renders the text so that its box fits inside the margins
+// on both axes and returns the layer with its top-left corner in bounds
+func (p *ImageProcessor) fitTextWatermark(bounds image.Rectangle, req *models.WatermarkRequest) (*image.NRGBA, image.Point, error) {
+	margin := p.watermarkMargin(bounds, req)
+	available := image.Pt(bounds.Dx()-2*margin, bounds.Dy()-2*margin)
+	if available.X <= 0 || available.Y <= 0 {
+		available = bounds.Size()
+	}
+
+	size, box, err := p.fitTextSize(req, p.fontSize(req, bounds), available)
+	if err != nil {
+		return nil, image.Point{}, err
 	}
-	d.DrawString(req.Text)
+
+	layer, err := p.renderText(req, size)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	// Clip whatever still overflows, e.g. a stroke and shadow that are
+	// larger than the space left inside the margins
+	if box.X > available.X || box.Y > available.Y {
+		clip := image.Rect(0, 0, min(box.X, available.X), min(box.Y, available.Y))
+		layer = layer.SubImage(clip).(*image.NRGBA)
+	}
+
+	return layer, p.watermarkOrigin(bounds, layer.Bounds().Size(), req), nil
 }
 
-// encodeImage encodes image to specified format
-func (p *ImageProcessor) encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+// encodeImage encodes image to specified format, embedding metadata for JPEG and PNG
+func (p *ImageProcessor) encodeImage(w io.Writer, img image.Image, format string, opts encodeOptions) error {
 	switch format {
 	case "jpeg", "jpg":
-		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		if opts.metadata.empty() {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: opts.quality})
+		}
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: opts.quality}); err != nil {
+			return err
+		}
+		return writeJPEGMetadata(w, buf.Bytes(), opts.metadata)
 	case "png":
-		return png.Encode(w, img)
+		if opts.metadata.empty() {
+			return png.Encode(w, img)
+		}
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return err
+		}
+		return writePNGMetadata(w, buf.Bytes(), opts.metadata)
 	case "webp":
-		return png.Encode(w, img)
+		return webp.Encode(w, img, &webp.Options{Lossless: opts.lossless, Quality: float32(opts.quality)})
+	case "gif":
+		return encodeGIF(w, img)
+	case "bmp":
+		return bmp.Encode(w, img)
+	case "tiff", "tif":
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate})
 	default:
-		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: opts.quality})
 	}
 }
 
+// encodeGIF encodes opaque images with the default Plan 9 palette. Images
+// with transparency get the web-safe palette plus a transparent index; GIF
+// has no partial alpha, so pixels below half opacity become transparent
+// and the rest opaque.
+func encodeGIF(w io.Writer, img image.Image) error {
+	if isOpaque(img) {
+		return gif.Encode(w, img, nil)
+	}
+
+	bounds := img.Bounds()
+	opaque := image.NewNRGBA(bounds)
+	draw.Draw(opaque, bounds, img, bounds.Min, draw.Src)
+	alpha := make([]uint8, 0, bounds.Dx()*bounds.Dy())
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		alpha = append(alpha, opaque.Pix[i])
+		opaque.Pix[i] = 0xff
+	}
+
+	// Index 0 is transparent; opaque colours never quantize to it
+	pal := append(color.Palette{color.Transparent}, palette.WebSafe...)
+	paletted := image.NewPaletted(bounds, pal)
+	draw.FloydSteinberg.Draw(paletted, bounds, opaque, bounds.Min)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if alpha[y*bounds.Dx()+x] < gifAlphaThreshold {
+				paletted.Pix[y*paletted.Stride+x] = 0
+			}
+		}
+	}
+
+	return gif.Encode(w, paletted, nil)
+}
+
 // Helper functions
 func (p *ImageProcessor) processImageJob(i int, files []multipart.File, req *models.AdvancedProcessingRequest, results []models.BatchImage) {
 	if i >= len(files) {
 		return
 	}
 
-	buffer, _, _, err := p.ProcessImage(files[i], req)
+	buffer, format, _, err := p.ProcessImage(files[i], req)
 	if err != nil {
 		results[i] = models.BatchImage{
 			Error: fmt.Sprintf("failed to process image %d: %v", i, err),
@@ -218,6 +684,7 @@ func (p *ImageProcessor) processImageJob(i int, files []multipart.File, req *mod
 
 	results[i] = models.BatchImage{
 		Buffer:   buffer,
+		Format:   format,
 		FileSize: int64(buffer.Len()),
 	}
 }
@@ -234,15 +701,34 @@ func (p *ImageProcessor) resetFilePointer(file multipart.File) {
 }
 
 func (p *ImageProcessor) getOutputFormat(originalFormat string, request *models.AdvancedProcessingRequest) string {
-	if request.Resize != nil && request.Resize.Format != "" {
-		return request.Resize.Format
+	if output := request.Output(); output != nil && output.Format != "" {
+		return output.Format
 	}
 	return originalFormat
 }
 
 func (p *ImageProcessor) getQuality(req *models.AdvancedProcessingRequest) int {
-	if req.Resize != nil && req.Resize.Quality > 0 {
-		return min(100, max(1, req.Resize.Quality))
+	if output := req.Output(); output != nil && output.Quality > 0 {
+		return min(100, max(1, output.Quality))
 	}
 	return DefaultQuality
 }
+
+func (p *ImageProcessor) isLossless(req *models.AdvancedProcessingRequest) bool {
+	output := req.Output()
+	return output != nil && output.Lossless
+}
+
+// getMetadata reads the source metadata and applies the request's metadata policy
+func (p *ImageProcessor) getMetadata(file multipart.File, req *models.AdvancedProcessingRequest) *imageMetadata {
+	if req.Metadata == "" || req.Metadata == models.MetadataStrip {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, p.getFileSize(file)))
+	if err != nil {
+		return nil
+	}
+
+	return filterMetadata(readMetadata(data), req.Metadata, !req.DisableAutoOrient)
+}
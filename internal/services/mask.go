@@ -0,0 +1,100 @@
+package services
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+// DefaultMaskRadius is the corner radius used when a rounded mask has none
+var DefaultMaskRadius = models.Length{Value: 10, Percent: true}
+
+// maskImage makes everything outside the shape transparent. Circles are
+// cropped to a centred square first. Edges are anti-aliased from the
+// signed distance of each pixel centre to the shape outline.
+func (p *ImageProcessor) maskImage(img image.Image, req *models.MaskRequest) image.Image {
+	var dst *image.NRGBA
+	if req.Shape == models.MaskCircle {
+		side := min(img.Bounds().Dx(), img.Bounds().Dy())
+		dst = imaging.CropCenter(img, side, side)
+	} else {
+		dst = imaging.Clone(img)
+	}
+
+	width, height := dst.Bounds().Dx(), dst.Bounds().Dy()
+	halfW, halfH := float64(width)/2, float64(height)/2
+
+	var distance func(x, y float64) float64
+	switch req.Shape {
+	case models.MaskCircle, models.MaskEllipse:
+		distance = func(x, y float64) float64 { return ellipseDistance(x, y, halfW, halfH) }
+	default:
+		radius := req.Radius
+		if radius.IsZero() {
+			radius = DefaultMaskRadius
+		}
+		r := min(float64(radius.Resolve(min(width, height))), halfW, halfH)
+		distance = func(x, y float64) float64 { return roundedRectDistance(x, y, halfW, halfH, r) }
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			coverage := min(1, max(0, 0.5-distance(float64(x)+0.5-halfW, float64(y)+0.5-halfH)))
+			if coverage < 1 {
+				i := dst.PixOffset(x, y) + 3
+				dst.Pix[i] = uint8(math.Round(float64(dst.Pix[i]) * coverage))
+			}
+		}
+	}
+
+	return dst
+}
+
+// roundedRectDistance is the signed distance from a point relative to the
+// centre to a rectangle of half size (halfW, halfH) with corner radius r
+func roundedRectDistance(x, y, halfW, halfH, r float64) float64 {
+	qx := math.Abs(x) - (halfW - r)
+	qy := math.Abs(y) - (halfH - r)
+	outside := math.Hypot(max(qx, 0), max(qy, 0))
+	inside := min(max(qx, qy), 0)
+	return outside + inside - r
+}
+
+// ellipseDistance approximates the signed distance to an ellipse with radii
+// a and b by dividing the implicit function by its gradient length
+func ellipseDistance(x, y, a, b float64) float64 {
+	f := x*x/(a*a) + y*y/(b*b) - 1
+	gx, gy := 2*x/(a*a), 2*y/(b*b)
+	gradient := math.Hypot(gx, gy)
+	if gradient == 0 {
+		return -min(a, b)
+	}
+	return f / gradient
+}
+
+// hasAlpha reports whether the format can store transparency
+func hasAlpha(format string) bool {
+	switch format {
+	case "jpeg", "jpg":
+		return false
+	default:
+		return true
+	}
+}
+
+// isOpaque reports whether every pixel of img is fully opaque
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+// flattenImage composites img onto a solid background, removing transparency
+func flattenImage(img image.Image, background string) image.Image {
+	bounds := img.Bounds()
+	canvas := imaging.New(bounds.Dx(), bounds.Dy(), colorOrDefault(background))
+	return imaging.Overlay(canvas, img, image.Point{}, 1.0)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestMaskedGIFKeepsTransparency(t *testing.T) {
+	p := NewImageProcessor()
+
+	src := &bytes.Buffer{}
+	if err := p.encodeImage(src, quadrantImage(64, 64), "png", encodeOptions{}); err != nil {
+		t.Fatalf("encodeImage: %v", err)
+	}
+
+	req := &models.AdvancedProcessingRequest{
+		Resize: &models.ResizeRequest{Width: 64, Height: 64, Format: "gif"},
+		Mask:   &models.MaskRequest{Shape: models.MaskCircle},
+	}
+	buf, format, _, err := p.ProcessImage(memFile{bytes.NewReader(src.Bytes())}, req)
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	if format != "gif" {
+		t.Fatalf("format = %q, want gif", format)
+	}
+
+	img, err := gif.Decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	for _, corner := range []image.Point{{0, 0}, {63, 0}, {0, 63}, {63, 63}} {
+		if a := color.NRGBAModel.Convert(img.At(corner.X, corner.Y)).(color.NRGBA).A; a != 0 {
+			t.Errorf("corner %v has alpha %d, want 0", corner, a)
+		}
+	}
+
+	// Inside the circle the quadrant colours survive quantization
+	inside := map[image.Point]color.NRGBA{
+		{20, 20}: quadrantColors[0],
+		{43, 20}: quadrantColors[1],
+		{20, 43}: quadrantColors[2],
+		{43, 43}: quadrantColors[3],
+	}
+	for pt, want := range inside {
+		if got := color.NRGBAModel.Convert(img.At(pt.X, pt.Y)).(color.NRGBA); got != want {
+			t.Errorf("pixel at %v = %v, want %v", pt, got, want)
+		}
+	}
+}
+
+func TestEncodeGIFOpaqueUsesFullPalette(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := encodeGIF(buf, quadrantImage(8, 8)); err != nil {
+		t.Fatalf("encodeGIF: %v", err)
+	}
+
+	img, err := gif.Decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	pal := img.(*image.Paletted).Palette
+	for _, c := range pal {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			t.Fatalf("opaque image was encoded with a transparent palette entry %v", c)
+		}
+	}
+}
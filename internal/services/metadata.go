@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const (
+	maxJPEGSegmentSize = 65533
+	maxICCChunkSize    = maxJPEGSegmentSize - 14 // ICC_PROFILE\0 + sequence + total
+	maxInflatedSize    = 4 << 20                 // compressed PNG blocks inflating past this are dropped
+	pngIHDREnd         = 33                      // signature + IHDR chunk
+	pngXMPKeyword      = "XML:com.adobe.xmp"
+)
+
+var (
+	xmpHeader    = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader    = []byte("ICC_PROFILE\x00")
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	xmpGPSElement   = regexp.MustCompile(`(?s)<exif:GPS\w+[^>]*?(/>|>.*?</exif:GPS\w+>)`)
+	xmpGPSAttribute = regexp.MustCompile(`\s+exif:GPS\w+="[^"]*"`)
+
+	// Rights properties kept by the copyright policy
+	xmpRightsElements = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<dc:rights\b[^>]*?(/>|>.*?</dc:rights>)`),
+		regexp.MustCompile(`(?s)<dc:creator\b[^>]*?(/>|>.*?</dc:creator>)`),
+		regexp.MustCompile(`(?s)<xmpRights:\w+\b[^>]*?(/>|>.*?</xmpRights:\w+>)`),
+	}
+	xmpRightsAttribute = regexp.MustCompile(`\sxmpRights:\w+="[^"]*"`)
+)
+
+const xmpRightsPacket = "<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" + `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmpRights="http://ns.adobe.com/xap/1.0/rights/"%s>
+%s
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// imageMetadata holds the metadata blocks carried over from the source image
+type imageMetadata struct {
+	exif []byte
+	xmp  []byte
+	icc  []byte
+}
+
+func (m *imageMetadata) empty() bool {
+	return m == nil || (len(m.exif) == 0 && len(m.xmp) == 0 && len(m.icc) == 0)
+}
+
+// readMetadata extracts EXIF, XMP and ICC blocks from a JPEG or PNG file
+func readMetadata(data []byte) *imageMetadata {
+	switch {
+	case len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8:
+		return readJPEGMetadata(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return readPNGMetadata(data)
+	default:
+		return nil
+	}
+}
+
+func readJPEGMetadata(data []byte) *imageMetadata {
+	m := &imageMetadata{}
+	iccChunks := map[byte][]byte{}
+
+	offset := 2
+	for offset+4 <= len(data) && data[offset] == 0xFF {
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		end := offset + 2 + int(binary.BigEndian.Uint16(data[offset+2:]))
+		if end > len(data) {
+			break
+		}
+		segment := data[offset+4 : end]
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(segment, exifHeader):
+			m.exif = segment[len(exifHeader):]
+		case marker == 0xE1 && bytes.HasPrefix(segment, xmpHeader):
+			m.xmp = segment[len(xmpHeader):]
+		case marker == 0xE2 && bytes.HasPrefix(segment, iccHeader) && len(segment) > len(iccHeader)+2:
+			iccChunks[segment[len(iccHeader)]] = segment[len(iccHeader)+2:]
+		}
+
+		offset = end
+	}
+
+	sequence := make([]int, 0, len(iccChunks))
+	for seq := range iccChunks {
+		sequence = append(sequence, int(seq))
+	}
+	sort.Ints(sequence)
+	for _, seq := range sequence {
+		m.icc = append(m.icc, iccChunks[byte(seq)]...)
+	}
+
+	return m
+}
+
+func readPNGMetadata(data []byte) *imageMetadata {
+	m := &imageMetadata{}
+
+	offset := len(pngSignature)
+	for offset+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		kind := string(data[offset+4 : offset+8])
+		if kind == "IDAT" || kind == "IEND" || offset+12+length > len(data) {
+			break
+		}
+		chunk := data[offset+8 : offset+8+length]
+
+		switch kind {
+		case "eXIf":
+			m.exif = chunk
+		case "iCCP":
+			if name := bytes.IndexByte(chunk, 0); name >= 0 && name+2 <= len(chunk) {
+				m.icc = inflate(chunk[name+2:])
+			}
+		case "iTXt":
+			m.xmp = readPNGXMP(chunk, m.xmp)
+		}
+
+		offset += 12 + length
+	}
+
+	return m
+}
+
+// readPNGXMP returns the XMP packet of an iTXt chunk, or current if the chunk holds other text
+func readPNGXMP(chunk, current []byte) []byte {
+	fields := bytes.SplitN(chunk, []byte{0}, 2)
+	if len(fields) != 2 || string(fields[0]) != pngXMPKeyword || len(fields[1]) < 2 {
+		return current
+	}
+
+	compressed := fields[1][0] == 1
+	rest := bytes.SplitN(fields[1][2:], []byte{0}, 3) // language, translated keyword, text
+	if len(rest) != 3 {
+		return current
+	}
+
+	if compressed {
+		return inflate(rest[2])
+	}
+	return rest[2]
+}
+
+// filterMetadata applies the metadata policy. When upright is set the image
+// was rotated on decode, so the EXIF orientation is reset to avoid a double rotation.
+func filterMetadata(m *imageMetadata, policy string, upright bool) *imageMetadata {
+	if m.empty() || policy == "" || policy == models.MetadataStrip {
+		return nil
+	}
+
+	filtered := &imageMetadata{icc: m.icc}
+
+	exif, err := parseEXIF(m.exif)
+	if err != nil {
+		exif = &exifData{}
+	}
+
+	switch policy {
+	case models.MetadataKeep:
+		filtered.xmp = m.xmp
+	case models.MetadataStripGPS:
+		exif.gps = nil
+		filtered.xmp = xmpGPSAttribute.ReplaceAll(xmpGPSElement.ReplaceAll(m.xmp, nil), nil)
+	case models.MetadataCopyright:
+		exif.ifd0 = filterEntries(exif.ifd0, func(entry tiffEntry) bool {
+			return entry.tag == tagCopyright || entry.tag == tagArtist
+		})
+		exif.exif = nil
+		exif.gps = nil
+		filtered.xmp = copyrightXMP(m.xmp)
+	}
+
+	if upright {
+		exif.setOrientation(1)
+	}
+	if !exif.empty() {
+		filtered.exif = exif.encode()
+	}
+
+	return filtered
+}
+
+// copyrightXMP returns a new XMP packet holding only the dc:rights,
+// dc:creator and xmpRights properties of xmp, or nil if it has none
+func copyrightXMP(xmp []byte) []byte {
+	var elements [][]byte
+	for _, re := range xmpRightsElements {
+		elements = append(elements, re.FindAll(xmp, -1)...)
+	}
+	attributes := xmpRightsAttribute.FindAll(xmp, -1)
+	if len(elements) == 0 && len(attributes) == 0 {
+		return nil
+	}
+
+	return []byte(fmt.Sprintf(xmpRightsPacket, bytes.Join(attributes, nil), bytes.Join(elements, []byte("\n"))))
+}
+
+// writeJPEGMetadata writes the encoded JPEG with metadata segments inserted after SOI
+func writeJPEGMetadata(w io.Writer, jpg []byte, m *imageMetadata) error {
+	buf := &bytes.Buffer{}
+	buf.Write(jpg[:2])
+
+	writeJPEGSegment(buf, 0xE1, exifHeader, m.exif)
+	writeJPEGSegment(buf, 0xE1, xmpHeader, m.xmp)
+
+	total := (len(m.icc) + maxICCChunkSize - 1) / maxICCChunkSize
+	if total <= 255 {
+		for i := 0; i < total; i++ {
+			chunk := m.icc[i*maxICCChunkSize : min(len(m.icc), (i+1)*maxICCChunkSize)]
+			writeJPEGSegment(buf, 0xE2, append(append([]byte(nil), iccHeader...), byte(i+1), byte(total)), chunk)
+		}
+	}
+
+	buf.Write(jpg[2:])
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeJPEGSegment writes an APPn segment, skipping payloads that are empty or too large
+func writeJPEGSegment(buf *bytes.Buffer, marker byte, header, payload []byte) {
+	size := len(header) + len(payload)
+	if len(payload) == 0 || size > maxJPEGSegmentSize {
+		return
+	}
+
+	buf.Write([]byte{0xFF, marker})
+	binary.Write(buf, binary.BigEndian, uint16(size+2))
+	buf.Write(header)
+	buf.Write(payload)
+}
+
+// writePNGMetadata writes the encoded PNG with metadata chunks inserted after IHDR
+func writePNGMetadata(w io.Writer, png []byte, m *imageMetadata) error {
+	buf := &bytes.Buffer{}
+	buf.Write(png[:pngIHDREnd])
+
+	if len(m.icc) > 0 {
+		writePNGChunk(buf, "iCCP", append([]byte("icc\x00\x00"), deflate(m.icc)...))
+	}
+	if len(m.exif) > 0 {
+		writePNGChunk(buf, "eXIf", m.exif)
+	}
+	if len(m.xmp) > 0 {
+		writePNGChunk(buf, "iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), m.xmp...))
+	}
+
+	buf.Write(png[pngIHDREnd:])
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writePNGChunk(buf *bytes.Buffer, kind string, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(kind)
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// inflate decompresses a zlib block, returning nil if it is invalid or
+// expands beyond maxInflatedSize
+func inflate(data []byte) []byte {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxInflatedSize+1))
+	if err != nil || len(out) > maxInflatedSize {
+		return nil
+	}
+	return out
+}
+
+func deflate(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestInflateLimitsOutput(t *testing.T) {
+	small := bytes.Repeat([]byte("icc"), 1000)
+	if got := inflate(deflate(small)); !bytes.Equal(got, small) {
+		t.Errorf("inflate returned %d bytes, want %d", len(got), len(small))
+	}
+
+	bomb := deflate(make([]byte, maxInflatedSize+1))
+	if got := inflate(bomb); got != nil {
+		t.Errorf("inflate returned %d bytes for a block over the limit, want nil", len(got))
+	}
+
+	if got := inflate([]byte("not zlib")); got != nil {
+		t.Errorf("inflate returned %d bytes for invalid data, want nil", len(got))
+	}
+}
+
+const testXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about=""
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns:exif="http://ns.adobe.com/exif/1.0/"
+  xmlns:tiff="http://ns.adobe.com/tiff/1.0/"
+  xmlns:xmpRights="http://ns.adobe.com/xap/1.0/rights/"
+  tiff:Make="ACME Camera"
+  exif:GPSLatitude="10,46.5N"
+  xmpRights:Marked="True"
+  xmpRights:WebStatement="https://example.com/license">
+<dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li></rdf:Seq></dc:creator>
+<dc:rights><rdf:Alt><rdf:li xml:lang="x-default">© 2024 ACME</rdf:li></rdf:Alt></dc:rights>
+<dc:description><rdf:Alt><rdf:li xml:lang="x-default">Harbour at dusk</rdf:li></rdf:Alt></dc:description>
+<xmpRights:UsageTerms><rdf:Alt><rdf:li xml:lang="x-default">No reuse</rdf:li></rdf:Alt></xmpRights:UsageTerms>
+<exif:GPSAltitude>12/1</exif:GPSAltitude>
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>`
+
+func TestFilterMetadataCopyrightKeepsXMPRights(t *testing.T) {
+	filtered := filterMetadata(&imageMetadata{xmp: []byte(testXMP)}, models.MetadataCopyright, false)
+	if filtered == nil || len(filtered.xmp) == 0 {
+		t.Fatal("copyright policy dropped the XMP rights")
+	}
+	xmp := string(filtered.xmp)
+
+	for _, want := range []string{"© 2024 ACME", "Jane Doe", "No reuse", `xmpRights:Marked="True"`, "https://example.com/license"} {
+		if !strings.Contains(xmp, want) {
+			t.Errorf("filtered XMP is missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"GPS", "ACME Camera", "Harbour at dusk"} {
+		if strings.Contains(xmp, unwanted) {
+			t.Errorf("filtered XMP still contains %q", unwanted)
+		}
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(xmp))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("filtered XMP is not well-formed: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestFilterMetadataCopyrightWithoutRights(t *testing.T) {
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:tiff="http://ns.adobe.com/tiff/1.0/" tiff:Make="ACME"/></rdf:RDF></x:xmpmeta>`)
+
+	if filtered := filterMetadata(&imageMetadata{xmp: xmp}, models.MetadataCopyright, false); filtered != nil && len(filtered.xmp) > 0 {
+		t.Errorf("expected no XMP without rights properties, got %q", filtered.xmp)
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"mime/multipart"
+	"sort"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const (
+	DefaultPaletteSize = 5
+	MaxPaletteSize     = 16
+
+	paletteSample     = 128 // longest side of the copy the palette is computed from
+	paletteIterations = 10
+	paletteMinAlpha   = 128 // pixels more transparent than this are ignored
+)
+
+// ExtractPalette decodes an uploaded image of at most maxSize bytes, rotates
+// it upright and returns its palette
+func (p *ImageProcessor) ExtractPalette(file multipart.File, maxSize int64, size int) (*models.Palette, error) {
+	if err := p.ValidateImage(file, maxSize); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return p.Palette(applyOrientation(img, readOrientation(file)), size)
+}
+
+// Palette finds up to size representative colours: median cut seeds the
+// clusters and a few k-means passes refine them. Colours are sorted by the
+// share of opaque pixels they cover.
+func (p *ImageProcessor) Palette(img image.Image, size int) (*models.Palette, error) {
+	size = max(1, min(size, MaxPaletteSize))
+
+	small := imaging.Fit(img, paletteSample, paletteSample, imaging.Box)
+	var pixels [][3]float64
+	for i := 0; i < len(small.Pix); i += 4 {
+		if small.Pix[i+3] >= paletteMinAlpha {
+			pixels = append(pixels, [3]float64{float64(small.Pix[i]), float64(small.Pix[i+1]), float64(small.Pix[i+2])})
+		}
+	}
+	if len(pixels) == 0 {
+		return nil, fmt.Errorf("image has no opaque pixels")
+	}
+
+	centroids, counts := kMeans(pixels, medianCut(pixels, size))
+
+	order := make([]int, len(centroids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return counts[order[a]] > counts[order[b]] })
+
+	palette := &models.Palette{}
+	for _, i := range order {
+		if counts[i] == 0 {
+			continue
+		}
+		rgb := [3]int{}
+		for c := 0; c < 3; c++ {
+			rgb[c] = int(math.Round(centroids[i][c]))
+		}
+		palette.Colors = append(palette.Colors, models.PaletteColor{
+			Hex:        fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2]),
+			RGB:        rgb,
+			Percentage: math.Round(float64(counts[i])/float64(len(pixels))*10000) / 100,
+		})
+	}
+	palette.Dominant = palette.Colors[0]
+
+	return palette, nil
+}
+
+// medianCut splits the colour space into up to size boxes, always cutting the
+// box with the widest channel range at its median, and returns their means
+func medianCut(pixels [][3]float64, size int) [][3]float64 {
+	boxes := [][][3]float64{pixels}
+
+	for len(boxes) < size {
+		best, bestChannel, bestRange := -1, 0, 0.0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, spread := widestChannel(box)
+			if spread > bestRange {
+				best, bestChannel, bestRange = i, channel, spread
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(a, b int) bool { return box[a][bestChannel] < box[b][bestChannel] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	centroids := make([][3]float64, len(boxes))
+	for i, box := range boxes {
+		centroids[i] = meanColor(box)
+	}
+	return centroids
+}
+
+// kMeans refines the centroids and returns them with their pixel counts
+func kMeans(pixels [][3]float64, centroids [][3]float64) ([][3]float64, []int) {
+	assignment := make([]int, len(pixels))
+	counts := make([]int, len(centroids))
+
+	for iteration := 0; iteration < paletteIterations; iteration++ {
+		changed := false
+		sums := make([][3]float64, len(centroids))
+		for i := range counts {
+			counts[i] = 0
+		}
+
+		for i, px := range pixels {
+			nearest, nearestDist := 0, math.MaxFloat64
+			for j, c := range centroids {
+				if d := colorDistance(px, c); d < nearestDist {
+					nearest, nearestDist = j, d
+				}
+			}
+			if iteration == 0 || assignment[i] != nearest {
+				changed = true
+			}
+			assignment[i] = nearest
+			counts[nearest]++
+			for c := 0; c < 3; c++ {
+				sums[nearest][c] += px[c]
+			}
+		}
+
+		for j := range centroids {
+			if counts[j] > 0 {
+				for c := 0; c < 3; c++ {
+					centroids[j][c] = sums[j][c] / float64(counts[j])
+				}
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	return centroids, counts
+}
+
+func widestChannel(box [][3]float64) (int, float64) {
+	low := [3]float64{255, 255, 255}
+	var high [3]float64
+	for _, px := range box {
+		for c := 0; c < 3; c++ {
+			low[c] = min(low[c], px[c])
+			high[c] = max(high[c], px[c])
+		}
+	}
+
+	channel := 0
+	for c := 1; c < 3; c++ {
+		if high[c]-low[c] > high[channel]-low[channel] {
+			channel = c
+		}
+	}
+	return channel, high[channel] - low[channel]
+}
+
+func meanColor(box [][3]float64) [3]float64 {
+	var sum [3]float64
+	for _, px := range box {
+		for c := 0; c < 3; c++ {
+			sum[c] += px[c]
+		}
+	}
+	n := float64(len(box))
+	return [3]float64{sum[0] / n, sum[1] / n, sum[2] / n}
+}
+
+func colorDistance(a, b [3]float64) float64 {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
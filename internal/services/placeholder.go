@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const (
+	// LQIPSize is the longest side of the inline preview image
+	LQIPSize    = 32
+	lqipQuality = 50
+
+	blurHashSample     = 32  // longest side of the image the BlurHash is computed from
+	blurHashComponents = 4   // components along the longer side
+	thumbHashSample    = 100 // ThumbHash inputs must fit in 100x100
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Placeholder computes the requested hash and an LQIP data URI for img
+func (p *ImageProcessor) Placeholder(img image.Image, kind string) (*models.Placeholder, error) {
+	result := &models.Placeholder{}
+
+	switch kind {
+	case models.PlaceholderBlurHash:
+		result.BlurHash = blurHash(img)
+	case models.PlaceholderThumbHash:
+		result.ThumbHash = thumbHash(img)
+	}
+
+	lqip, err := lqipDataURI(img)
+	if err != nil {
+		return nil, err
+	}
+	result.LQIP = lqip
+
+	return result, nil
+}
+
+// lqipDataURI encodes a tiny copy of img as a base64 data URI: JPEG for
+// opaque images and PNG when transparency must survive
+func lqipDataURI(img image.Image) (string, error) {
+	small := imaging.Fit(img, LQIPSize, LQIPSize, imaging.Box)
+
+	var buf bytes.Buffer
+	mime := "image/jpeg"
+	var err error
+	if small.Opaque() {
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: lqipQuality})
+	} else {
+		mime = "image/png"
+		err = png.Encode(&buf, small)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode placeholder: %w", err)
+	}
+
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// blurHash encodes img with the BlurHash algorithm (https://blurha.sh),
+// using 4 components along the longer side and 3 along the shorter
+func blurHash(img image.Image) string {
+	small := imaging.Fit(img, blurHashSample, blurHashSample, imaging.Box)
+	width, height := small.Bounds().Dx(), small.Bounds().Dy()
+
+	componentsX, componentsY := blurHashComponents, blurHashComponents-1
+	if height > width {
+		componentsX, componentsY = componentsY, componentsX
+	}
+
+	// Convert once to linear light
+	linear := make([][3]float64, width*height)
+	for i := range linear {
+		for c := 0; c < 3; c++ {
+			linear[i][c] = srgbToLinear(small.Pix[i*4+c])
+		}
+	}
+
+	factors := make([][3]float64, 0, componentsX*componentsY)
+	for j := 0; j < componentsY; j++ {
+		for i := 0; i < componentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) * basisY
+					for c := 0; c < 3; c++ {
+						factor[c] += basis * linear[y*width+x][c]
+					}
+				}
+			}
+
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((componentsX-1)+(componentsY-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = max(actualMax, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantisedMax := int(max(0, min(82, math.Floor(actualMax*166-0.5))))
+		maximumValue = float64(quantisedMax+1) / 166
+		hash.WriteString(encodeBase83(quantisedMax, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+
+	for _, f := range ac {
+		quantise := func(v float64) int {
+			return int(max(0, min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quantise(f[0])*19*19+quantise(f[1])*19+quantise(f[2]), 2))
+	}
+
+	return hash.String()
+}
+
+func encodeBase83(value, length int) string {
+	result := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		result[i-1] = base83Chars[digit]
+	}
+	return string(result)
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := max(0, min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+// thumbHash encodes img with the ThumbHash algorithm
+// (https://evanw.github.io/thumbhash/) and returns it base64 encoded
+func thumbHash(img image.Image) string {
+	small := imaging.Fit(img, thumbHashSample, thumbHashSample, imaging.Box)
+	width, height := small.Bounds().Dx(), small.Bounds().Dy()
+	count := width * height
+
+	// Average colour, weighted by alpha
+	var avgR, avgG, avgB, avgA float64
+	for i := 0; i < count; i++ {
+		alpha := float64(small.Pix[i*4+3]) / 255
+		avgR += alpha / 255 * float64(small.Pix[i*4])
+		avgG += alpha / 255 * float64(small.Pix[i*4+1])
+		avgB += alpha / 255 * float64(small.Pix[i*4+2])
+		avgA += alpha
+	}
+	if avgA > 0 {
+		avgR, avgG, avgB = avgR/avgA, avgG/avgA, avgB/avgA
+	}
+
+	transparent := avgA < float64(count)
+	limit := 7.0
+	if transparent {
+		limit = 5 // fewer luminance bits when alpha is stored
+	}
+	longest := float64(max(width, height))
+	lx := max(1, int(math.Round(limit*float64(width)/longest)))
+	ly := max(1, int(math.Round(limit*float64(height)/longest)))
+
+	// Convert to LPQA, composited over the average colour
+	l := make([]float64, count)
+	pc := make([]float64, count)
+	q := make([]float64, count)
+	a := make([]float64, count)
+	for i := 0; i < count; i++ {
+		alpha := float64(small.Pix[i*4+3]) / 255
+		r := avgR*(1-alpha) + alpha/255*float64(small.Pix[i*4])
+		g := avgG*(1-alpha) + alpha/255*float64(small.Pix[i*4+1])
+		b := avgB*(1-alpha) + alpha/255*float64(small.Pix[i*4+2])
+		l[i] = (r + g + b) / 3
+		pc[i] = (r+g)/2 - b
+		q[i] = r - g
+		a[i] = alpha
+	}
+
+	encodeChannel := func(channel []float64, nx, ny int) (float64, []float64, float64) {
+		var dc, scale float64
+		var ac []float64
+		fx := make([]float64, width)
+		for cy := 0; cy < ny; cy++ {
+			for cx := 0; cx*ny < nx*(ny-cy); cx++ {
+				for x := 0; x < width; x++ {
+					fx[x] = math.Cos(math.Pi / float64(width) * float64(cx) * (float64(x) + 0.5))
+				}
+				f := 0.0
+				for y := 0; y < height; y++ {
+					fy := math.Cos(math.Pi / float64(height) * float64(cy) * (float64(y) + 0.5))
+					for x := 0; x < width; x++ {
+						f += channel[x+y*width] * fx[x] * fy
+					}
+				}
+				f /= float64(count)
+				if cx > 0 || cy > 0 {
+					ac = append(ac, f)
+					scale = max(scale, math.Abs(f))
+				} else {
+					dc = f
+				}
+			}
+		}
+		if scale > 0 {
+			for i := range ac {
+				ac[i] = 0.5 + 0.5/scale*ac[i]
+			}
+		}
+		return dc, ac, scale
+	}
+
+	lDC, lAC, lScale := encodeChannel(l, max(3, lx), max(3, ly))
+	pDC, pAC, pScale := encodeChannel(pc, 3, 3)
+	qDC, qAC, qScale := encodeChannel(q, 3, 3)
+
+	round := func(v float64) int { return int(math.Round(v)) }
+	isLandscape := width > height
+
+	header24 := round(63*lDC) | round(31.5+31.5*pDC)<<6 | round(31.5+31.5*qDC)<<12 | round(31*lScale)<<18
+	if transparent {
+		header24 |= 1 << 23
+	}
+	header16 := round(63*pScale)<<3 | round(63*qScale)<<9
+	if isLandscape {
+		header16 |= ly | 1<<15
+	} else {
+		header16 |= lx
+	}
+
+	hash := []byte{byte(header24), byte(header24 >> 8), byte(header24 >> 16), byte(header16), byte(header16 >> 8)}
+	channels := [][]float64{lAC, pAC, qAC}
+	if transparent {
+		aDC, aAC, aScale := encodeChannel(a, 5, 5)
+		hash = append(hash, byte(round(15*aDC)|round(15*aScale)<<4))
+		channels = append(channels, aAC)
+	}
+
+	// Pack the AC terms as 4-bit values, two per byte
+	start, index := len(hash), 0
+	for _, ac := range channels {
+		for _, f := range ac {
+			pos := start + index>>1
+			if pos >= len(hash) {
+				hash = append(hash, 0)
+			}
+			hash[pos] |= byte(round(15*f) << ((index & 1) << 2))
+			index++
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(hash)
+}
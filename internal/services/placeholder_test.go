@@ -0,0 +1,84 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func solidImage(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// The expected hashes below are worked out by hand from the reference
+// encoders rather than taken from this implementation, so they catch drift
+// in the component layout, quantisation and bit packing.
+func TestBlurHashKnownVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		// Every factor is zero: size flag 'L' for 4x3 components, quantised
+		// maximum 0, DC 0 and eleven AC terms of 9*19*19+9*19+9 = "fQ"
+		{"black", solidImage(32, 24, color.NRGBA{0, 0, 0, 255}), "L00000" + strings.Repeat("fQ", 11)},
+		// The reference basis is cos(πix/width), whose sum over a row is 1 for
+		// odd i and 0 for even i, so a flat image still has AC terms of 1/16
+		// (i odd, j=0), 1/12 (i=0, j=1) and 1/384 (i odd, j=1). The largest,
+		// 1/12, quantises to 13 and the terms to 17, 18 and 11 per channel.
+		{"white", solidImage(32, 24, color.NRGBA{255, 255, 255, 255}), "LDTSUA_3fQ_3~qoffQoffQfQfQfQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blurHash(tt.img); got != tt.want {
+				t.Errorf("blurHash = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbHashKnownVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		// L, P and Q are all zero: header 0x020800 (P and Q DC at 32) and
+		// 0x8005 (landscape, ly = round(7*24/32) = 5), then 22+5+5 zero AC nibbles
+		{"opaque black", solidImage(32, 24, color.NRGBA{0, 0, 0, 255}), base64.StdEncoding.EncodeToString(append(
+			[]byte{0x00, 0x08, 0x02, 0x05, 0x80}, make([]byte, 16)...))},
+		// As above with the alpha bit set, ly = round(5*24/32) = 4, a zero
+		// alpha byte and 13+5+5+14 zero AC nibbles
+		{"transparent", solidImage(32, 24, color.NRGBA{}), base64.StdEncoding.EncodeToString(append(
+			[]byte{0x00, 0x08, 0x82, 0x04, 0x80, 0x00}, make([]byte, 19)...))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thumbHash(tt.img); got != tt.want {
+				t.Errorf("thumbHash = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbHashHeader(t *testing.T) {
+	// Opaque red is L = 1/3, P = 1/2 and Q = 1, so the DC fields are 21,
+	// round(31.5+31.5/2) = 47 and 63. The AC terms of a flat image are only
+	// rounding noise, so just the header is fixed.
+	raw, err := base64.StdEncoding.DecodeString(thumbHash(solidImage(32, 24, color.NRGBA{255, 0, 0, 255})))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []byte{0xd5, 0xfb, 0x03, 0x05, 0x80}
+	if len(raw) < len(want) || !bytes.Equal(raw[:len(want)], want) {
+		t.Errorf("header = % x, want % x", raw[:min(len(raw), len(want))], want)
+	}
+}
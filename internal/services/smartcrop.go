@@ -0,0 +1,164 @@
+package services
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	smartCropAnalysisSize  = 256
+	smartCropSteps         = 32
+	smartCropHistogramBins = 16
+
+	smartCropEdgeWeight       = 0.45
+	smartCropEntropyWeight    = 0.35
+	smartCropSaturationWeight = 0.2
+	smartCropCenterBias       = 0.1
+)
+
+// smartCrop returns the width x height window of img with the most visual
+// interest, scored by edge density, luminance entropy and colour saturation.
+// The returned rectangle is in the coordinate space of img.
+func smartCrop(img image.Image, width, height int) image.Rectangle {
+	bounds := img.Bounds()
+	width = max(1, min(width, bounds.Dx()))
+	height = max(1, min(height, bounds.Dy()))
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return bounds
+	}
+
+	// Analyse a downscaled copy; scores are resolution independent
+	scale := min(1, float64(smartCropAnalysisSize)/float64(max(bounds.Dx(), bounds.Dy())))
+	small := imaging.Resize(img, max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale)), imaging.Box)
+	maps := newSaliencyMaps(small)
+
+	sw, sh := small.Bounds().Dx(), small.Bounds().Dy()
+	winW := max(1, min(sw, int(math.Round(float64(width)*float64(sw)/float64(bounds.Dx())))))
+	winH := max(1, min(sh, int(math.Round(float64(height)*float64(sh)/float64(bounds.Dy())))))
+
+	bestX, bestY, bestScore := 0, 0, math.Inf(-1)
+	for _, y := range searchOffsets(sh - winH) {
+		for _, x := range searchOffsets(sw - winW) {
+			score := maps.score(x, y, winW, winH)
+			if score > bestScore {
+				bestX, bestY, bestScore = x, y, score
+			}
+		}
+	}
+
+	// Map the winning window back to full resolution
+	x := int(math.Round(float64(bestX) * float64(bounds.Dx()) / float64(sw)))
+	y := int(math.Round(float64(bestY) * float64(bounds.Dy()) / float64(sh)))
+	x = min(x, bounds.Dx()-width)
+	y = min(y, bounds.Dy()-height)
+
+	return image.Rect(x, y, x+width, y+height).Add(bounds.Min)
+}
+
+// searchOffsets returns about smartCropSteps evenly stepped window offsets
+// in [0, span], always including span so windows flush with the far edge
+// are scored too
+func searchOffsets(span int) []int {
+	step := max(1, span/smartCropSteps)
+	offsets := make([]int, 0, span/step+2)
+	for offset := 0; offset < span; offset += step {
+		offsets = append(offsets, offset)
+	}
+	return append(offsets, span)
+}
+
+// saliencyMaps holds summed-area tables so any window can be scored in O(bins)
+type saliencyMaps struct {
+	width, height int
+	edges         []float64
+	saturation    []float64
+	histogram     [][]float64
+}
+
+func newSaliencyMaps(img *image.NRGBA) *saliencyMaps {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	m := &saliencyMaps{
+		width:      w,
+		height:     h,
+		edges:      make([]float64, (w+1)*(h+1)),
+		saturation: make([]float64, (w+1)*(h+1)),
+		histogram:  make([][]float64, smartCropHistogramBins),
+	}
+	for i := range m.histogram {
+		m.histogram[i] = make([]float64, (w+1)*(h+1))
+	}
+
+	luma := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*img.Stride + x*4
+			r, g, b := float64(img.Pix[i]), float64(img.Pix[i+1]), float64(img.Pix[i+2])
+			luma[y*w+x] = 0.299*r + 0.587*g + 0.114*b
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*img.Stride + x*4
+			hi, lo := max(img.Pix[i], img.Pix[i+1], img.Pix[i+2]), min(img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+			sat := 0.0
+			if hi > 0 {
+				sat = float64(hi-lo) / float64(hi)
+			}
+
+			// Laplacian of luminance as a cheap edge detector
+			center := luma[y*w+x]
+			edge := 4*center -
+				luma[y*w+max(0, x-1)] - luma[y*w+min(w-1, x+1)] -
+				luma[max(0, y-1)*w+x] - luma[min(h-1, y+1)*w+x]
+			edge = min(1, math.Abs(edge)/255)
+
+			bin := min(smartCropHistogramBins-1, int(center)*smartCropHistogramBins/256)
+
+			j := (y+1)*(w+1) + x + 1
+			above, left, diag := j-(w+1), j-1, j-(w+1)-1
+			m.edges[j] = edge + m.edges[above] + m.edges[left] - m.edges[diag]
+			m.saturation[j] = sat + m.saturation[above] + m.saturation[left] - m.saturation[diag]
+			for b := range m.histogram {
+				value := 0.0
+				if b == bin {
+					value = 1
+				}
+				m.histogram[b][j] = value + m.histogram[b][above] + m.histogram[b][left] - m.histogram[b][diag]
+			}
+		}
+	}
+
+	return m
+}
+
+func (m *saliencyMaps) sum(table []float64, x, y, w, h int) float64 {
+	stride := m.width + 1
+	return table[(y+h)*stride+x+w] - table[y*stride+x+w] - table[(y+h)*stride+x] + table[y*stride+x]
+}
+
+// score combines normalized edge density, entropy and saturation of a window,
+// with a slight bias toward the image centre to break ties in flat images
+func (m *saliencyMaps) score(x, y, w, h int) float64 {
+	area := float64(w * h)
+
+	entropy := 0.0
+	for _, table := range m.histogram {
+		if count := m.sum(table, x, y, w, h); count > 0 {
+			p := count / area
+			entropy -= p * math.Log2(p)
+		}
+	}
+	entropy /= math.Log2(smartCropHistogramBins)
+
+	score := smartCropEdgeWeight*m.sum(m.edges, x, y, w, h)/area +
+		smartCropEntropyWeight*entropy +
+		smartCropSaturationWeight*m.sum(m.saturation, x, y, w, h)/area
+
+	dx := (float64(x)+float64(w)/2)/float64(m.width) - 0.5
+	dy := (float64(y)+float64(h)/2)/float64(m.height) - 0.5
+	return score * (1 - smartCropCenterBias*math.Hypot(dx, dy))
+}
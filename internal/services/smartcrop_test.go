@@ -0,0 +1,42 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSearchOffsetsIncludeFarEdge(t *testing.T) {
+	for _, span := range []int{0, 1, 31, 32, 33, 100, 255} {
+		offsets := searchOffsets(span)
+		if offsets[0] != 0 || offsets[len(offsets)-1] != span {
+			t.Errorf("searchOffsets(%d) = %v, want 0 first and %d last", span, offsets, span)
+		}
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] <= offsets[i-1] {
+				t.Errorf("searchOffsets(%d) = %v is not increasing", span, offsets)
+				break
+			}
+		}
+	}
+}
+
+func TestSmartCropFindsDetailAtFarEdge(t *testing.T) {
+	// A flat image with a detailed strip along the right and bottom edges,
+	// sized so the stepped search does not land on the last offset
+	img := image.NewNRGBA(image.Rect(0, 0, 255, 255))
+	for y := 0; y < 255; y++ {
+		for x := 0; x < 255; x++ {
+			c := color.NRGBA{128, 128, 128, 255}
+			if x >= 245 && y >= 245 && (x+y)%2 == 0 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	got := smartCrop(img, 100, 100)
+	if want := image.Rect(155, 155, 255, 255); got != want {
+		t.Errorf("smartCrop = %v, want %v", got, want)
+	}
+}
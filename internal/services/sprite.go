@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+const (
+	MaxSpriteImages    = 200
+	DefaultSpriteClass = "sprite"
+)
+
+// SpriteSheet is an encoded sheet with the frames of the images it holds,
+// in upload order; Errors lists the images that could not be decoded
+type SpriteSheet struct {
+	Buffer *bytes.Buffer
+	Format string
+	Image  image.Image
+	Frames []models.SpriteFrame
+	Errors []string
+}
+
+// BuildSpriteSheet decodes the images on the worker pool, optionally resizes
+// them to a uniform size and packs them into one transparent sheet. Files
+// larger than maxSize bytes are listed in Errors.
+func (p *ImageProcessor) BuildSpriteSheet(files []multipart.File, names []string, maxSize int64, req *models.SpriteRequest) (*SpriteSheet, error) {
+	images := make([]image.Image, len(files))
+	errs := make([]error, len(files))
+	p.runWorkers(len(files), func(i int) {
+		images[i], errs[i] = p.spriteImage(files[i], maxSize, req)
+	})
+
+	sheet := &SpriteSheet{}
+	var sprites []image.Image
+	var spriteNames []string
+	for i, err := range errs {
+		if err != nil {
+			sheet.Errors = append(sheet.Errors, fmt.Sprintf("failed to process image %d: %v", i, err))
+			continue
+		}
+		sprites = append(sprites, images[i])
+		spriteNames = append(spriteNames, names[i])
+	}
+	if len(sprites) == 0 {
+		return nil, fmt.Errorf("no images could be processed")
+	}
+
+	sizes := make([]image.Point, len(sprites))
+	for i, img := range sprites {
+		sizes[i] = img.Bounds().Size()
+	}
+	rects, size := packShelves(sizes, max(0, req.Padding))
+	if size.X > MaxCanvasSize || size.Y > MaxCanvasSize {
+		return nil, fmt.Errorf("sprite sheet %dx%d exceeds maximum size %d", size.X, size.Y, MaxCanvasSize)
+	}
+
+	canvas := image.NewNRGBA(image.Rectangle{Max: size})
+	classNames := uniqueClassNames(spriteNames)
+	for i, img := range sprites {
+		canvas = imaging.Paste(canvas, img, rects[i].Min)
+		sheet.Frames = append(sheet.Frames, models.SpriteFrame{
+			Name:   classNames[i],
+			X:      rects[i].Min.X,
+			Y:      rects[i].Min.Y,
+			Width:  rects[i].Dx(),
+			Height: rects[i].Dy(),
+		})
+	}
+
+	sheet.Format = req.Format
+	if sheet.Format == "" {
+		sheet.Format = models.FormatPNG
+	}
+
+	sheet.Buffer = &bytes.Buffer{}
+	if err := p.encodeImage(sheet.Buffer, canvas, sheet.Format, encodeOptions{quality: DefaultQuality, lossless: true}); err != nil {
+		return nil, fmt.Errorf("failed to encode sprite sheet: %w", err)
+	}
+	sheet.Image = canvas
+
+	return sheet, nil
+}
+
+// spriteImage decodes one image, rotates it upright and resizes it when a
+// uniform size is requested; fit defaults to contain on a transparent cell
+func (p *ImageProcessor) spriteImage(file multipart.File, maxSize int64, req *models.SpriteRequest) (image.Image, error) {
+	if err := p.ValidateImage(file, maxSize); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	img = applyOrientation(img, readOrientation(file))
+
+	if req.Width <= 0 && req.Height <= 0 {
+		return img, nil
+	}
+
+	fit := req.Fit
+	if fit == "" {
+		fit = models.FitContain
+	}
+
+	return p.resizeImage(img, &models.ResizeRequest{
+		Width:      req.Width,
+		Height:     req.Height,
+		Fit:        fit,
+		Background: "transparent",
+	}), nil
+}
+
+// packShelves places rectangles tallest first on shelves and returns them in
+// input order with the sheet size. A few shelf widths around the square root
+// of the total area are tried and the one giving the smallest sheet wins.
+func packShelves(sizes []image.Point, padding int) ([]image.Rectangle, image.Point) {
+	order := make([]int, len(sizes))
+	area, widest := 0, 0
+	for i, s := range sizes {
+		order[i] = i
+		area += (s.X + padding) * (s.Y + padding)
+		widest = max(widest, s.X)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sizes[order[a]].Y > sizes[order[b]].Y
+	})
+
+	var bestRects []image.Rectangle
+	var bestSize image.Point
+	for _, factor := range []float64{1, 1.25, 1.5, 2} {
+		maxWidth := max(widest, int(math.Ceil(math.Sqrt(float64(area))*factor)))
+		rects, size := packShelvesWidth(sizes, order, padding, maxWidth)
+		if bestRects == nil || size.X*size.Y < bestSize.X*bestSize.Y {
+			bestRects, bestSize = rects, size
+		}
+	}
+
+	return bestRects, bestSize
+}
+
+// packShelvesWidth fills shelves left to right in the given order, starting
+// a new shelf when the next rectangle would pass maxWidth
+func packShelvesWidth(sizes []image.Point, order []int, padding, maxWidth int) ([]image.Rectangle, image.Point) {
+	rects := make([]image.Rectangle, len(sizes))
+
+	var sheet image.Point
+	x, y, shelfHeight := 0, 0, 0
+	for _, i := range order {
+		s := sizes[i]
+		if x > 0 && x+s.X > maxWidth {
+			x, y = 0, y+shelfHeight+padding
+			shelfHeight = 0
+		}
+		rects[i] = image.Rect(x, y, x+s.X, y+s.Y)
+		sheet.X = max(sheet.X, x+s.X)
+		sheet.Y = max(sheet.Y, y+s.Y)
+		x += s.X + padding
+		shelfHeight = max(shelfHeight, s.Y)
+	}
+
+	return rects, sheet
+}
+
+// uniqueClassNames turns file names into CSS-safe names, numbering duplicates.
+// Every file keeps its own name if it is free, so a suffix never takes a name
+// that a later file would use as is.
+func uniqueClassNames(names []string) []string {
+	bases := make([]string, len(names))
+	reserved := make(map[string]bool, len(names))
+	for i, name := range names {
+		bases[i] = cssIdentifier(strings.TrimSuffix(name, filepath.Ext(name)))
+		if bases[i] == "" {
+			bases[i] = fmt.Sprintf("image-%d", i+1)
+		}
+		reserved[bases[i]] = true
+	}
+
+	used := make(map[string]bool, len(names))
+	result := make([]string, len(names))
+	for i, base := range bases {
+		name := base
+		if used[name] {
+			for n := 2; used[name] || reserved[name]; n++ {
+				name = fmt.Sprintf("%s-%d", base, n)
+			}
+		}
+		used[name] = true
+		result[i] = name
+	}
+	return result
+}
+
+// cssIdentifier lower-cases name and replaces anything but letters, digits,
+// '-' and '_' with '-'
+func cssIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// SpriteCSS renders a stylesheet with a base class for the sheet and one
+// class per frame
+func SpriteCSS(url, prefix string, frames []models.SpriteFrame) string {
+	if prefix = cssIdentifier(prefix); prefix == "" {
+		prefix = DefaultSpriteClass
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".%s {\n  background-image: url(%q);\n  background-repeat: no-repeat;\n  display: inline-block;\n}\n", prefix, url)
+	for _, f := range frames {
+		fmt.Fprintf(&b, ".%s-%s {\n  width: %dpx;\n  height: %dpx;\n  background-position: %s %s;\n}\n",
+			prefix, f.Name, f.Width, f.Height, cssOffset(f.X), cssOffset(f.Y))
+	}
+	return b.String()
+}
+
+// cssOffset formats a negative background offset, writing zero without a unit
+func cssOffset(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("-%dpx", n)
+}
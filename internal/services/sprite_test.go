@@ -0,0 +1,84 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"mime/multipart"
+	"reflect"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestUniqueClassNames(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"home.png", "search.svg.png", "User Icon.PNG"}, []string{"home", "search-svg", "user-icon"}},
+		{[]string{"a.png", "a.png", "a.png"}, []string{"a", "a-2", "a-3"}},
+		{[]string{"a.png", "a.png", "a-2.png"}, []string{"a", "a-3", "a-2"}},
+		{[]string{"a-2.png", "a.png", "a.png"}, []string{"a-2", "a", "a-3"}},
+		{[]string{"a.png", "a-2.png", "a.png", "a-2.png"}, []string{"a", "a-2", "a-3", "a-2-2"}},
+		{[]string{"###.png", "image-1.png"}, []string{"image-1", "image-1-2"}},
+	}
+
+	for _, tt := range tests {
+		got := uniqueClassNames(tt.names)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("uniqueClassNames(%q) = %q, want %q", tt.names, got, tt.want)
+		}
+
+		seen := make(map[string]bool)
+		for _, name := range got {
+			if seen[name] {
+				t.Errorf("uniqueClassNames(%q) repeats %q", tt.names, name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+func TestGIFSpriteSheetKeepsTransparency(t *testing.T) {
+	p := NewImageProcessor()
+
+	var files []multipart.File
+	for _, size := range []image.Point{{40, 20}, {20, 40}} {
+		buf := &bytes.Buffer{}
+		if err := p.encodeImage(buf, quadrantImage(size.X, size.Y), "png", encodeOptions{}); err != nil {
+			t.Fatalf("encodeImage: %v", err)
+		}
+		files = append(files, memFile{bytes.NewReader(buf.Bytes())})
+	}
+
+	// Uniform contain cells letterbox both images onto transparent padding
+	req := &models.SpriteRequest{Width: 40, Height: 40, Padding: 4, Format: "gif"}
+	sheet, err := p.BuildSpriteSheet(files, []string{"wide.png", "tall.png"}, MaxFileSize, req)
+	if err != nil {
+		t.Fatalf("BuildSpriteSheet: %v", err)
+	}
+
+	img, err := gif.Decode(sheet.Buffer)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	alphaAt := func(x, y int) uint8 {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).A
+	}
+	for _, frame := range sheet.Frames {
+		// The wide image is letterboxed top and bottom, the tall one left and right
+		if frame.Name == "wide" {
+			if a := alphaAt(frame.X+20, frame.Y+2); a != 0 {
+				t.Errorf("letterbox of %s has alpha %d, want 0", frame.Name, a)
+			}
+		} else if a := alphaAt(frame.X+2, frame.Y+20); a != 0 {
+			t.Errorf("letterbox of %s has alpha %d, want 0", frame.Name, a)
+		}
+		if a := alphaAt(frame.X+20, frame.Y+20); a != 0xff {
+			t.Errorf("centre of %s has alpha %d, want 255", frame.Name, a)
+		}
+	}
+}
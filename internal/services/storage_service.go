@@ -84,6 +84,16 @@ func (s *StorageService) Upload(ctx context.Context, buffer *bytes.Buffer, filen
 	return publicURL.SignedURL, nil
 }
 
+// Download fetches a stored file from Supabase Storage
+func (s *StorageService) Download(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.sbClient.DownloadFile(s.bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from supabase: %w", err)
+	}
+
+	return data, nil
+}
+
 func (s *StorageService) SetCache(ctx context.Context, cacheKey string, data []byte) error {
 	return s.redisClient.Set(ctx, cacheKey, data, s.cacheDuration).Err()
 }
@@ -103,20 +113,21 @@ func (s *StorageService) GenerateCacheKey(originalFilename string, request *mode
 	var keyParts []string
 	keyParts = append(keyParts, originalFilename)
 
-	// Processing parameters - more efficient concatenation
-	if request.Resize != nil {
-		keyParts = append(keyParts, fmt.Sprintf("resize_%d_%d_%d_%s",
-			request.Resize.Width, request.Resize.Height, request.Resize.Quality, request.Resize.Format))
+	// Steps are numbered so the same operations in a different order hash differently
+	for i, op := range request.Pipeline() {
+		keyParts = append(keyParts, fmt.Sprintf("op%d_%s", i, operationKey(op)))
 	}
 
-	if request.Crop != nil {
-		keyParts = append(keyParts, fmt.Sprintf("crop_%d_%d_%d_%d",
-			request.Crop.X, request.Crop.Y, request.Crop.Width, request.Crop.Height))
+	if request.DisableAutoOrient {
+		keyParts = append(keyParts, "no_auto_orient")
 	}
 
-	if request.Watermark != nil {
-		keyParts = append(keyParts, fmt.Sprintf("watermark_%s_%s_%.2f",
-			request.Watermark.Text, request.Watermark.Position, request.Watermark.Opacity))
+	if request.Metadata != "" {
+		keyParts = append(keyParts, "metadata_"+request.Metadata)
+	}
+
+	if request.Flatten != "" {
+		keyParts = append(keyParts, "flatten_"+request.Flatten)
 	}
 
 	combined := strings.Join(keyParts, "_")
@@ -175,3 +186,54 @@ func (s *StorageService) generateStorageKey(filename string) string {
 
 	return fmt.Sprintf("processed/%s_%d_%s%s", name, timestamp, uuid, ext)
 }
+
+// operationKey serialises every parameter of a pipeline step
+func operationKey(op models.Operation) string {
+	switch {
+	case op.Resize != nil:
+		return fmt.Sprintf("resize_%d_%d_%d_%s_%t_%s_%s_%t_%s_%t_%s",
+			op.Resize.Width, op.Resize.Height, op.Resize.Quality, op.Resize.Format, op.Resize.Lossless,
+			op.Resize.Fit, op.Resize.Background, op.Resize.WithoutEnlargement, op.Resize.Filter,
+			op.Resize.SharpenOnDownscale, op.Resize.Position)
+	case op.Crop != nil:
+		return fmt.Sprintf("crop_%s_%s_%s_%s_%s_%s_%s",
+			op.Crop.X, op.Crop.Y, op.Crop.Width, op.Crop.Height,
+			op.Crop.Mode, op.Crop.AspectRatio, op.Crop.Gravity)
+	case op.Extend != nil:
+		return fmt.Sprintf("extend_%s_%s_%s_%s_%d_%d_%s_%s_%s",
+			op.Extend.Top, op.Extend.Right, op.Extend.Bottom, op.Extend.Left,
+			op.Extend.Width, op.Extend.Height, op.Extend.Gravity, op.Extend.Fill, op.Extend.Background)
+	case op.Rotate != nil:
+		return fmt.Sprintf("rotate_%g_%s", op.Rotate.Angle, op.Rotate.Background)
+	case op.Flip != "":
+		return "flip_" + op.Flip
+	case op.Adjust != nil:
+		return fmt.Sprintf("adjust_%g_%g_%g_%g_%g",
+			op.Adjust.Brightness, op.Adjust.Contrast, op.Adjust.Gamma, op.Adjust.Saturation, op.Adjust.Hue)
+	case op.Blur != 0:
+		return fmt.Sprintf("blur_%g", op.Blur)
+	case op.Sharpen != nil:
+		return fmt.Sprintf("sharpen_%g_%g_%d", op.Sharpen.Amount, op.Sharpen.Radius, op.Sharpen.Threshold)
+	case op.Watermark != nil:
+		wm := op.Watermark
+		key := fmt.Sprintf("watermark_%s_%s_%s_%.2f_%s_%s_%g_%v_font_%s_%s_%s_%d_%s_%s_%d",
+			wm.Text, wm.ImageURL, wm.Position, watermarkOpacity(wm), wm.OffsetX, wm.OffsetY, wm.Scale, wm.Margin,
+			wm.Font, wm.FontSize, wm.Color, wm.StrokeWidth, wm.StrokeColor, wm.ShadowColor, wm.ShadowOffset)
+		if wm.Mode == models.WatermarkModeTile {
+			angle := "default"
+			if wm.Angle != nil {
+				angle = fmt.Sprintf("%g", *wm.Angle)
+			}
+			key += fmt.Sprintf("_tile_%s_%s", wm.Spacing, angle)
+		}
+		return key
+	case op.Border != nil:
+		return fmt.Sprintf("border_%d_%s_%d_%s_%s_%d_%g_%s",
+			op.Border.Width, op.Border.Color, op.Border.Padding, op.Border.PaddingColor,
+			op.Border.ShadowColor, op.Border.ShadowOffset, op.Border.ShadowBlur, op.Border.Background)
+	case op.Mask != nil:
+		return fmt.Sprintf("mask_%s_%s", op.Mask.Shape, op.Mask.Radius)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-fonts/liberation/liberationmonobold"
+	"github.com/go-fonts/liberation/liberationmonoregular"
+	"github.com/go-fonts/liberation/liberationsansbold"
+	"github.com/go-fonts/liberation/liberationsansbolditalic"
+	"github.com/go-fonts/liberation/liberationsansitalic"
+	"github.com/go-fonts/liberation/liberationsansregular"
+	"github.com/go-fonts/liberation/liberationserifbold"
+	"github.com/go-fonts/liberation/liberationserifbolditalic"
+	"github.com/go-fonts/liberation/liberationserifitalic"
+	"github.com/go-fonts/liberation/liberationserifregular"
+	"github.com/phambaophuc/image-resize/internal/models"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	DefaultFont      = "sans"
+	DefaultTextColor = "#c8c8c8"
+	// DefaultFontSize is the text height relative to the image width
+	DefaultFontSize = 4.0
+	MinFontSize     = 8
+
+	maxTextFitPasses = 4
+)
+
+// bundledFonts are the fonts available without a FONT_DIR. Liberation covers
+// Latin Extended Additional, so Vietnamese diacritics render correctly.
+var bundledFonts = map[string][]byte{
+	"sans":              liberationsansregular.TTF,
+	"sans-bold":         liberationsansbold.TTF,
+	"sans-italic":       liberationsansitalic.TTF,
+	"sans-bold-italic":  liberationsansbolditalic.TTF,
+	"serif":             liberationserifregular.TTF,
+	"serif-bold":        liberationserifbold.TTF,
+	"serif-italic":      liberationserifitalic.TTF,
+	"serif-bold-italic": liberationserifbolditalic.TTF,
+	"mono":              liberationmonoregular.TTF,
+	"mono-bold":         liberationmonobold.TTF,
+}
+
+// LoadFont returns a parsed font by name, looking at bundled fonts first and
+// then at <FontDir>/<name>.ttf or .otf. Parsed fonts are cached.
+func (p *ImageProcessor) LoadFont(name string) (*opentype.Font, error) {
+	if name == "" {
+		name = DefaultFont
+	}
+
+	p.fontsMu.RLock()
+	f, ok := p.fonts[name]
+	p.fontsMu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	data, err := p.readFont(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err = opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid font %q: %w", name, err)
+	}
+
+	p.fontsMu.Lock()
+	p.fonts[name] = f
+	p.fontsMu.Unlock()
+
+	return f, nil
+}
+
+func (p *ImageProcessor) readFont(name string) ([]byte, error) {
+	if data, ok := bundledFonts[name]; ok {
+		return data, nil
+	}
+
+	if p.fontDir == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("unknown font %q", name)
+	}
+
+	for _, ext := range []string{".ttf", ".otf"} {
+		if data, err := os.ReadFile(filepath.Join(p.fontDir, name+ext)); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown font %q", name)
+}
+
+// textLayout is the measured box of a watermark text at one font size
+type textLayout struct {
+	face   font.Face
+	size   image.Point
+	ascent int
+	stroke int
+	shadow int
+}
+
+// layoutText measures the watermark text, its shadow and stroke without
+// drawing it. The caller must close the returned face.
+func (p *ImageProcessor) layoutText(req *models.WatermarkRequest, size float64) (*textLayout, error) {
+	f, err := p.LoadFont(req.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+
+	stroke := max(0, req.StrokeWidth)
+	shadow := 0
+	if req.ShadowColor != "" {
+		shadow = req.ShadowOffset
+		if shadow <= 0 {
+			shadow = max(1, int(size/20))
+		}
+	}
+
+	metrics := face.Metrics()
+	advance := font.MeasureString(face, req.Text).Ceil()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+
+	return &textLayout{
+		face:   face,
+		size:   image.Pt(advance+2*stroke+shadow, ascent+descent+2*stroke+shadow),
+		ascent: ascent,
+		stroke: stroke,
+		shadow: shadow,
+	}, nil
+}
+
+// measureText returns the size of the layer renderText would draw
+func (p *ImageProcessor) measureText(req *models.WatermarkRequest, size float64) (image.Point, error) {
+	layout, err := p.layoutText(req, size)
+	if err != nil {
+		return image.Point{}, err
+	}
+	layout.face.Close()
+	return layout.size, nil
+}
+
+// renderText draws the watermark text, its shadow and stroke onto a
+// transparent layer sized to the measured text box: the advance width and
+// the font's ascent plus descent, so the layer can be anchored exactly
+func (p *ImageProcessor) renderText(req *models.WatermarkRequest, size float64) (*image.NRGBA, error) {
+	layout, err := p.layoutText(req, size)
+	if err != nil {
+		return nil, err
+	}
+	defer layout.face.Close()
+
+	face, stroke, shadow := layout.face, layout.stroke, layout.shadow
+	layer := image.NewNRGBA(image.Rectangle{Max: layout.size})
+	baseline := image.Pt(stroke, stroke+layout.ascent)
+
+	drawAt := func(offset image.Point, c color.Color) {
+		d := &font.Drawer{
+			Dst:  layer,
+			Src:  image.NewUniform(c),
+			Face: face,
+			Dot:  fixed.P(baseline.X+offset.X, baseline.Y+offset.Y),
+		}
+		d.DrawString(req.Text)
+	}
+
+	if shadow > 0 {
+		drawAt(image.Pt(shadow, shadow), colorOrDefault(req.ShadowColor))
+	}
+
+	if stroke > 0 {
+		strokeColor := colorOrDefault(req.StrokeColor)
+		for _, offset := range strokeOffsets(stroke) {
+			drawAt(offset, strokeColor)
+		}
+	}
+
+	textColor := req.Color
+	if textColor == "" {
+		textColor = DefaultTextColor
+	}
+	drawAt(image.Point{}, colorOrDefault(textColor))
+
+	return layer, nil
+}
+
+// fontSize resolves the requested size; percentages are relative to the image
+// width. The result is never taller than the image.
+func (p *ImageProcessor) fontSize(req *models.WatermarkRequest, bounds image.Rectangle) float64 {
+	size := req.FontSize
+	if size.IsZero() {
+		size = models.Length{Value: DefaultFontSize, Percent: true}
+	}
+	return float64(max(MinFontSize, min(size.Resolve(bounds.Dx()), bounds.Dy())))
+}
+
+// fitTextSize shrinks the font size until the measured text box fits inside
+// available on both axes. Hinted advances do not scale linearly, so a few
+// passes may be needed; the returned box may still overflow after the last one.
+func (p *ImageProcessor) fitTextSize(req *models.WatermarkRequest, size float64, available image.Point) (float64, image.Point, error) {
+	box, err := p.measureText(req, size)
+	if err != nil {
+		return 0, image.Point{}, err
+	}
+
+	for i := 0; i < maxTextFitPasses && size > 1 && (box.X > available.X || box.Y > available.Y); i++ {
+		size = max(1, size*min(float64(available.X)/float64(box.X), float64(available.Y)/float64(box.Y)))
+		if box, err = p.measureText(req, size); err != nil {
+			return 0, image.Point{}, err
+		}
+	}
+
+	return size, box, nil
+}
+
+// strokeOffsets returns points on circles up to radius, used to draw a text outline
+func strokeOffsets(radius int) []image.Point {
+	var offsets []image.Point
+	for r := 1; r <= radius; r++ {
+		steps := max(8, int(2*math.Pi*float64(r)))
+		for i := 0; i < steps; i++ {
+			angle := 2 * math.Pi * float64(i) / float64(steps)
+			offsets = append(offsets, image.Pt(
+				int(math.Round(float64(r)*math.Cos(angle))),
+				int(math.Round(float64(r)*math.Sin(angle))),
+			))
+		}
+	}
+	return offsets
+}
+
+// compositeLayer blends a watermark layer onto img at origin with the given opacity
+func compositeLayer(img draw.Image, layer image.Image, origin image.Point, opacity float64) {
+	opacity = min(1.0, max(0.0, opacity))
+	mask := image.NewUniform(color.Alpha{uint8(math.Round(255 * opacity))})
+	target := image.Rectangle{Min: origin, Max: origin.Add(layer.Bounds().Size())}
+	draw.DrawMask(img, target, layer, layer.Bounds().Min, mask, image.Point{}, draw.Over)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestFitTextWatermarkStaysInBounds(t *testing.T) {
+	p := NewImageProcessor()
+
+	positions := []string{
+		models.PositionTopLeft,
+		models.PositionTopCenter,
+		models.PositionTopRight,
+		models.PositionLeftCenter,
+		models.PositionCenter,
+		models.PositionRightCenter,
+		models.PositionBottomLeft,
+		models.PositionBottomCenter,
+		models.PositionBottomRight,
+	}
+	texts := map[string]string{
+		"short": "© ACME",
+		"long":  strings.Repeat("Bản quyền thuộc về ACME Corporation ", 8),
+	}
+	images := map[string]image.Rectangle{
+		"landscape": image.Rect(0, 0, 640, 480),
+		"banner":    image.Rect(0, 0, 800, 24),
+		"narrow":    image.Rect(0, 0, 40, 600),
+		"offset":    image.Rect(100, 50, 420, 290),
+	}
+	sizes := map[string]models.Length{
+		"default": {},
+		"huge":    {Value: 400},
+	}
+
+	for _, position := range positions {
+		for textName, text := range texts {
+			for imageName, bounds := range images {
+				for sizeName, fontSize := range sizes {
+					name := strings.Join([]string{position, textName, imageName, sizeName}, "/")
+					t.Run(name, func(t *testing.T) {
+						req := &models.WatermarkRequest{
+							Text:        text,
+							Position:    position,
+							FontSize:    fontSize,
+							StrokeWidth: 2,
+							ShadowColor: "#000000",
+						}
+
+						layer, origin, err := p.fitTextWatermark(bounds, req)
+						if err != nil {
+							t.Fatalf("fitTextWatermark: %v", err)
+						}
+
+						box := image.Rectangle{Min: origin, Max: origin.Add(layer.Bounds().Size())}
+						if box.Empty() {
+							t.Fatalf("text box %v is empty", box)
+						}
+						if !box.In(bounds) {
+							t.Errorf("text box %v is outside image %v", box, bounds)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestDrawTextWatermarkInkInsideMargins(t *testing.T) {
+	p := NewImageProcessor()
+	bounds := image.Rect(0, 0, 300, 40)
+	req := &models.WatermarkRequest{
+		Text:     strings.Repeat("WATERMARK ", 10),
+		Position: models.PositionBottomRight,
+		FontSize: models.Length{Value: 200},
+		Color:    "#ff0000",
+	}
+
+	img := image.NewRGBA(bounds)
+	p.drawTextWatermark(img, req)
+
+	margin := p.watermarkMargin(bounds, req)
+	inner := bounds.Inset(margin)
+	inked := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.RGBAAt(x, y).A == 0 {
+				continue
+			}
+			inked = true
+			if !image.Pt(x, y).In(inner) {
+				t.Fatalf("ink at (%d,%d) is outside the margins %v", x, y, inner)
+			}
+		}
+	}
+	if !inked {
+		t.Fatal("no text was drawn")
+	}
+}
+
+func TestFontSizeClampedToImageHeight(t *testing.T) {
+	p := NewImageProcessor()
+	bounds := image.Rect(0, 0, 2000, 120)
+
+	tests := []struct {
+		size models.Length
+		want float64
+	}{
+		{models.Length{}, 80},
+		{models.Length{Value: 50}, 50},
+		{models.Length{Value: 10000}, 120},
+		{models.Length{Value: 100, Percent: true}, 120},
+		{models.Length{Value: 1}, MinFontSize},
+	}
+
+	for _, tt := range tests {
+		if got := p.fontSize(&models.WatermarkRequest{FontSize: tt.size}, bounds); got != tt.want {
+			t.Errorf("fontSize(%s) = %g, want %g", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestTileStampFitsImage(t *testing.T) {
+	p := NewImageProcessor()
+	bounds := image.Rect(0, 0, 400, 300)
+	req := &models.WatermarkRequest{
+		Text:     strings.Repeat("CONFIDENTIAL ", 20),
+		Mode:     models.WatermarkModeTile,
+		FontSize: models.Length{Value: 10000},
+	}
+
+	stamp, err := p.tileStamp(bounds, req)
+	if err != nil {
+		t.Fatalf("tileStamp: %v", err)
+	}
+	if size := stamp.Bounds().Size(); size.X > bounds.Dx() || size.Y > bounds.Dy() {
+		t.Errorf("tile stamp %v is larger than the image %v", size, bounds.Size())
+	}
+}
+
+func TestTextWatermarkOpacity(t *testing.T) {
+	p := NewImageProcessor()
+	bounds := image.Rect(0, 0, 200, 100)
+	zero, half := 0.0, 0.5
+
+	tests := []struct {
+		name    string
+		opacity *float64
+		want    uint8 // most opaque text pixel
+	}{
+		{"default", nil, 255},
+		{"half", &half, 128},
+		{"zero", &zero, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(bounds)
+			p.drawTextWatermark(img, &models.WatermarkRequest{
+				Text:     "ACME",
+				Position: models.PositionCenter,
+				FontSize: models.Length{Value: 40},
+				Opacity:  tt.opacity,
+			})
+
+			var strongest uint8
+			for i := 3; i < len(img.Pix); i += 4 {
+				strongest = max(strongest, img.Pix[i])
+			}
+			if strongest != tt.want {
+				t.Errorf("most opaque text pixel has alpha %d, want %d", strongest, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+// DefaultTileAngle is the tile rotation in degrees clockwise; negative values
+// make the watermark rise from left to right
+const DefaultTileAngle = -30.0
+
+// drawTiledWatermark repeats the watermark across the whole image. The stamp
+// is rotated by the tile angle and laid out in rows, with every other row
+// shifted by half a step so the pattern has no straight gaps. Offsets move
+// the pattern; position and margin do not apply.
+func (p *ImageProcessor) drawTiledWatermark(img *image.RGBA, req *models.WatermarkRequest) {
+	bounds := img.Bounds()
+
+	stamp, err := p.tileStamp(bounds, req)
+	if err != nil || stamp == nil {
+		return
+	}
+
+	angle := DefaultTileAngle
+	if req.Angle != nil {
+		angle = *req.Angle
+	}
+	if angle != 0 {
+		stamp = imaging.Rotate(stamp, -angle, color.Transparent)
+	}
+
+	opacity := watermarkOpacity(req)
+	size := stamp.Bounds().Size()
+	gap := min(size.X, size.Y) / 2
+	if !req.Spacing.IsZero() {
+		gap = max(0, req.Spacing.Resolve(bounds.Dx()))
+	}
+	stepX, stepY := size.X+gap, size.Y+gap
+
+	// Start one step before the image so the pattern covers the edges
+	startX := bounds.Min.X + floorMod(req.OffsetX.Resolve(bounds.Dx()), stepX) - stepX
+	startY := bounds.Min.Y + floorMod(req.OffsetY.Resolve(bounds.Dy()), stepY) - stepY
+
+	for row, y := 0, startY; y < bounds.Max.Y; row, y = row+1, y+stepY {
+		x := startX
+		if row%2 == 1 {
+			x -= stepX / 2
+		}
+		for ; x < bounds.Max.X; x += stepX {
+			target := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x, y).Add(size)}
+			if target.Overlaps(bounds) {
+				compositeLayer(img, stamp, target.Min, opacity)
+			}
+		}
+	}
+}
+
+// tileStamp builds the repeated unit: the scaled logo, the rendered text, or
+// the logo centred above the text when both are set
+func (p *ImageProcessor) tileStamp(bounds image.Rectangle, req *models.WatermarkRequest) (image.Image, error) {
+	var logo, text *image.NRGBA
+	if req.Image != nil {
+		logo = p.scaleWatermark(bounds, req)
+	}
+	if req.Text != "" {
+		// A stamp larger than the image would never repeat, so shrink it to fit
+		size, _, err := p.fitTextSize(req, p.fontSize(req, bounds), bounds.Size())
+		if err != nil {
+			return nil, err
+		}
+		if text, err = p.renderText(req, size); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case logo == nil && text == nil:
+		return nil, nil
+	case text == nil:
+		return logo, nil
+	case logo == nil:
+		return text, nil
+	}
+
+	lineGap := text.Bounds().Dy() / 4
+	width := max(logo.Bounds().Dx(), text.Bounds().Dx())
+	stamp := image.NewNRGBA(image.Rect(0, 0, width, logo.Bounds().Dy()+lineGap+text.Bounds().Dy()))
+
+	logoAt := image.Pt((width-logo.Bounds().Dx())/2, 0)
+	draw.Draw(stamp, logo.Bounds().Add(logoAt), logo, image.Point{}, draw.Over)
+
+	textAt := image.Pt((width-text.Bounds().Dx())/2, logo.Bounds().Dy()+lineGap)
+	draw.Draw(stamp, text.Bounds().Add(textAt), text, image.Point{}, draw.Over)
+
+	return stamp, nil
+}
+
+// floorMod returns a modulo b in the range [0, b)
+func floorMod(a, b int) int {
+	return ((a % b) + b) % b
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/phambaophuc/image-resize/internal/models"
+)
+
+func TestImageWatermarkDefaultOpacity(t *testing.T) {
+	p := NewImageProcessor()
+
+	logo := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	for i := range logo.Pix {
+		logo.Pix[i] = 0xff
+	}
+	zero := 0.0
+
+	tests := []struct {
+		name    string
+		opacity *float64
+		want    color.RGBA
+	}{
+		{"omitted", nil, color.RGBA{255, 255, 255, 255}},
+		{"zero", &zero, color.RGBA{0, 0, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+			for i := 3; i < len(img.Pix); i += 4 {
+				img.Pix[i] = 0xff
+			}
+
+			p.drawImageWatermark(img, &models.WatermarkRequest{
+				Image:    logo,
+				ImageURL: "file://logo.png",
+				Position: models.PositionCenter,
+				Opacity:  tt.opacity,
+			})
+
+			if got := img.RGBAAt(50, 50); got != tt.want {
+				t.Errorf("centre pixel = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -28,7 +28,9 @@ func main() {
 		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
 
-	processor := services.NewImageProcessor()
+	processor := services.NewImageProcessor(services.ProcessorOptions{
+		FontDir: cfg.Storage.FontDir,
+	})
 
 	storage, err := services.NewStorageService(cfg)
 	if err != nil {